@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
 
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	admissionv1 "k8s.io/api/admission/v1"
-	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 var (
@@ -29,20 +40,78 @@ var (
 		Version: "v1",
 		Kind:    "HTTPProxy",
 	}
+	ingressResource = metav1.GroupVersionKind{
+		Group:   "networking.k8s.io",
+		Version: "v1",
+		Kind:    "Ingress",
+	}
+	tlsCertificateDelegationResource = metav1.GroupVersionKind{
+		Group:   "projectcontour.io",
+		Version: "v1",
+		Kind:    "TLSCertificateDelegation",
+	}
+	extensionServiceResource = metav1.GroupVersionKind{
+		Group:   "projectcontour.io",
+		Version: "v1alpha1",
+		Kind:    "ExtensionService",
+	}
 )
 
+// KindValidator decodes and validates the raw object of an admission request
+// for a single resource kind.
+type KindValidator func(Validator, []byte) (ValidationResponse, error)
+
+var kindValidators = map[metav1.GroupVersionKind]KindValidator{
+	httpProxyResource:                validateHTTPProxy,
+	ingressResource:                  validateIngress,
+	tlsCertificateDelegationResource: validateTLSCertificateDelegation,
+	extensionServiceResource:         validateExtensionService,
+}
+
+// UpdateValidator validates the raw object of an UPDATE request together with
+// the raw object it replaces.
+type UpdateValidator func(validator Validator, old, raw []byte) (ValidationResponse, error)
+
+// updateValidators validate UPDATE requests of kinds that compare the object
+// with the one it replaces. Other kinds are validated like a CREATE.
+var updateValidators = map[metav1.GroupVersionKind]UpdateValidator{
+	httpProxyResource: validateHTTPProxyUpdate,
+}
+
+// deleteValidators validate the old object of DELETE requests. Deletes of
+// kinds without a validator are always allowed.
+var deleteValidators = map[metav1.GroupVersionKind]KindValidator{
+	httpProxyResource: validateHTTPProxyDelete,
+}
+
 func init() {
 	_ = admissionv1.AddToScheme(runtimeScheme)
 	_ = contourv1.AddToScheme(runtimeScheme)
+	_ = contourv1alpha1.AddToScheme(runtimeScheme)
+	// Only the core kinds looked up by rules are decoded from manifests.
+	runtimeScheme.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Namespace{}, &corev1.Service{})
+	_ = networkingv1.AddToScheme(runtimeScheme)
+	_ = gatewayv1beta1.AddToScheme(runtimeScheme)
+	_ = addPolicyTypes(runtimeScheme)
 }
 
-type Review func(*admissionv1.AdmissionReview)
+type Review func(context.Context, *admissionv1.AdmissionReview)
 
-func AdmissionMiddleware(review Review) http.Handler {
+// AdmissionMiddleware decodes AdmissionReviews, passes them to review and
+// writes back the response. Every recorder is given the completed review.
+func AdmissionMiddleware(review Review, recorders ...ReviewRecorder) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		defer req.Body.Close()
+
+		// The API server propagates its trace context when tracing is enabled.
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer().Start(ctx, "AdmissionMiddleware", trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		_, decodeSpan := tracer().Start(ctx, "decode")
 		data, err := io.ReadAll(req.Body)
 		if err != nil {
+			endSpan(decodeSpan, err)
 			slog.Error("Failed to read request body", "error", err.Error())
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -50,19 +119,41 @@ func AdmissionMiddleware(review Review) http.Handler {
 
 		obj, _, err := serializer.Decode(data, nil, nil)
 		if err != nil {
+			endSpan(decodeSpan, err)
 			slog.Error("Could not decode request body", "error", err.Error())
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 		admissionReview, ok := obj.(*admissionv1.AdmissionReview)
 		if !ok {
+			endSpan(decodeSpan, fmt.Errorf("unexpected type %T", obj))
 			slog.Error("Request was not an AdmissionReview", "type", fmt.Sprintf("%T", obj))
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		review(admissionReview)
+		decodeSpan.End()
+
+		if admissionReview.Request != nil {
+			span.SetAttributes(
+				attribute.String("admission.uid", string(admissionReview.Request.UID)),
+				attribute.String("admission.kind", admissionReview.Request.Kind.Kind),
+				attribute.String("admission.operation", string(admissionReview.Request.Operation)),
+				attribute.String("admission.namespace", admissionReview.Request.Namespace),
+				attribute.String("admission.name", admissionReview.Request.Name),
+			)
+		}
+
+		review(ctx, admissionReview)
+		for _, recorder := range recorders {
+			recorder.Record(admissionReview)
+		}
+		if admissionReview.Response != nil {
+			span.SetAttributes(attribute.Bool("admission.allowed", admissionReview.Response.Allowed))
+		}
 
+		_, encodeSpan := tracer().Start(ctx, "encode")
 		err = serializer.Encode(admissionReview, w)
+		endSpan(encodeSpan, err)
 		if err != nil {
 			slog.Error("Failed to encode response", "error", err.Error())
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -73,42 +164,91 @@ func AdmissionMiddleware(review Review) http.Handler {
 
 type HTTPProxyAdmissionHandler struct {
 	Validator Validator
+	// Policy optionally provides a validation policy applied on top of the
+	// Validator for each review.
+	Policy PolicySource
+	// Kinds restricts the resource kinds accepted by the handler. Every kind
+	// with a registered KindValidator is accepted when empty.
+	Kinds []metav1.GroupVersionKind
+	// Events optionally records events on the existing proxies a denied
+	// proxy conflicted with.
+	Events *DenialEvents
 }
 
 func (ah *HTTPProxyAdmissionHandler) Validate(review *admissionv1.AdmissionReview) {
+	ah.ValidateContext(context.Background(), review)
+}
+
+// ValidateContext validates the review as part of the request context, so the
+// rules and Store calls are traced with the request.
+func (ah *HTTPProxyAdmissionHandler) ValidateContext(ctx context.Context, review *admissionv1.AdmissionReview) {
 	resp := &admissionv1.AdmissionResponse{}
 	resp.UID = review.Request.UID
 	review.Response = resp
 
-	if !apiequality.Semantic.DeepEqual(review.Request.Kind, httpProxyResource) {
-		slog.Error("Review is not for HTTPProxy resource", "kind", review.Request.Kind.String(), "name", review.Request.Name)
+	start := time.Now()
+	logger := slog.Default().With(
+		"uid", review.Request.UID,
+		"user", review.Request.UserInfo.Username,
+		"groups", review.Request.UserInfo.Groups,
+		"operation", review.Request.Operation,
+		"kind", review.Request.Kind.Kind,
+		"namespace", review.Request.Namespace,
+		"name", review.Request.Name,
+	)
+	defer func() {
+		var message string
+		if resp.Result != nil {
+			message = resp.Result.Message
+		}
+		logger.Info("Admission decision", "allowed", resp.Allowed, "message", message, "warnings", resp.Warnings, "latency", time.Since(start))
+	}()
+
+	validate, ok := kindValidators[review.Request.Kind]
+	if !ok || (len(ah.Kinds) > 0 && !slices.Contains(ah.Kinds, review.Request.Kind)) {
+		logger.Error("Review is for unsupported resource", "kind", review.Request.Kind.String(), "name", review.Request.Name)
 		resp.Allowed = false
 		resp.Result = &metav1.Status{
 			Status:  metav1.StatusFailure,
 			Code:    http.StatusBadRequest,
 			Reason:  metav1.StatusReasonBadRequest,
-			Message: fmt.Sprintf("Review is not for HTTPProxy resource. Instead got %s with name %s", review.Request.Kind.String(), review.Request.Name),
+			Message: fmt.Sprintf("Review is for unsupported resource %s with name %s", review.Request.Kind.String(), review.Request.Name),
 		}
 		return
 	}
 
 	raw := review.Request.Object.Raw
-	proxy := contourv1.HTTPProxy{}
-	if _, _, err := serializer.Decode(raw, nil, &proxy); err != nil {
-		slog.Error("Failed to decode HTTPProxy", "error", err.Error())
-		resp.Allowed = false
-		resp.Result = &metav1.Status{
-			Status:  metav1.StatusFailure,
-			Code:    http.StatusInternalServerError,
-			Reason:  metav1.StatusReasonInternalError,
-			Message: err.Error(),
+	if review.Request.Operation == admissionv1.Delete {
+		validate, ok = deleteValidators[review.Request.Kind]
+		if !ok {
+			resp.Allowed = true
+			return
+		}
+		raw = review.Request.OldObject.Raw
+	}
+	if update, ok := updateValidators[review.Request.Kind]; ok && review.Request.Operation == admissionv1.Update {
+		old := review.Request.OldObject.Raw
+		validate = func(validator Validator, raw []byte) (ValidationResponse, error) {
+			return update(validator, old, raw)
+		}
+	}
+
+	var policy *HTTPProxyValidationPolicy
+	if ah.Policy != nil {
+		policy = ah.Policy.Current()
+	}
+	if policy != nil {
+		resp.AuditAnnotations = map[string]string{
+			"policy":            policy.Name,
+			"policy-generation": strconv.FormatInt(policy.Generation, 10),
 		}
-		return
 	}
 
-	validationResponse, err := ah.Validator.IsValidProxy(proxy)
+	validator := applyPolicy(ah.Validator, policy)
+	validator.Logger = logger
+	validator.Context = ctx
+	validationResponse, err := validate(validator, raw)
 	if err != nil {
-		slog.Error("Failed to validate HTTPProxy", "error", err.Error())
 		resp.Allowed = false
 		resp.Result = &metav1.Status{
 			Status:  metav1.StatusFailure,
@@ -119,6 +259,13 @@ func (ah *HTTPProxyAdmissionHandler) Validate(review *admissionv1.AdmissionRevie
 		return
 	}
 
+	if !validationResponse.Valid && enforcementMode(policy) == EnforcementModeWarn {
+		logger.Info("Allowing invalid resource in warn mode", "reason", validationResponse.Reason)
+		resp.Allowed = true
+		resp.Warnings = []string{validationResponse.Reason}
+		return
+	}
+
 	if !validationResponse.Valid {
 		resp.Allowed = false
 		resp.Result = &metav1.Status{
@@ -126,8 +273,123 @@ func (ah *HTTPProxyAdmissionHandler) Validate(review *admissionv1.AdmissionRevie
 			Code:    http.StatusBadRequest,
 			Reason:  metav1.StatusReasonBadRequest,
 			Message: validationResponse.Reason,
+			Details: statusDetails(review.Request, validationResponse.Violations),
+		}
+		if ah.Events != nil && len(validationResponse.Conflicts) > 0 {
+			ah.Events.Denied(review.Request, validationResponse.Conflicts)
 		}
 		return
 	}
 	resp.Allowed = true
 }
+
+// statusDetails reports each violation as a cause of the failure so clients
+// can see which fields were rejected.
+func statusDetails(req *admissionv1.AdmissionRequest, violations []Violation) *metav1.StatusDetails {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	details := &metav1.StatusDetails{
+		Name:  req.Name,
+		Group: req.Kind.Group,
+		Kind:  req.Kind.Kind,
+	}
+	for _, violation := range violations {
+		details.Causes = append(details.Causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("%s: %s", violation.Rule, violation.Message),
+			Field:   violation.Field,
+		})
+	}
+	return details
+}
+
+func validateHTTPProxy(validator Validator, raw []byte) (ValidationResponse, error) {
+	proxy := contourv1.HTTPProxy{}
+	if _, _, err := serializer.Decode(raw, nil, &proxy); err != nil {
+		validator.logger().Error("Failed to decode HTTPProxy", "error", err.Error())
+		return ValidationResponse{}, err
+	}
+
+	validationResponse, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		validator.logger().Error("Failed to validate HTTPProxy", "error", err.Error())
+	}
+	return validationResponse, err
+}
+
+func validateHTTPProxyUpdate(validator Validator, oldRaw, raw []byte) (ValidationResponse, error) {
+	old := contourv1.HTTPProxy{}
+	if _, _, err := serializer.Decode(oldRaw, nil, &old); err != nil {
+		validator.logger().Error("Failed to decode old HTTPProxy", "error", err.Error())
+		return ValidationResponse{}, err
+	}
+	proxy := contourv1.HTTPProxy{}
+	if _, _, err := serializer.Decode(raw, nil, &proxy); err != nil {
+		validator.logger().Error("Failed to decode HTTPProxy", "error", err.Error())
+		return ValidationResponse{}, err
+	}
+
+	if onlyConflictMarkerChanged(old, proxy) {
+		validator.logger().Info("Allowing update of the conflict marker only")
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	validator.OldProxy = &old
+	validationResponse, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		validator.logger().Error("Failed to validate HTTPProxy", "error", err.Error())
+	}
+	return validationResponse, err
+}
+
+func validateHTTPProxyDelete(validator Validator, raw []byte) (ValidationResponse, error) {
+	proxy := contourv1.HTTPProxy{}
+	if _, _, err := serializer.Decode(raw, nil, &proxy); err != nil {
+		validator.logger().Error("Failed to decode HTTPProxy", "error", err.Error())
+		return ValidationResponse{}, err
+	}
+
+	validationResponse, err := validator.IsDeletableProxy(proxy)
+	if err != nil {
+		validator.logger().Error("Failed to validate HTTPProxy delete", "error", err.Error())
+	}
+	return validationResponse, err
+}
+
+func validateIngress(validator Validator, raw []byte) (ValidationResponse, error) {
+	ingress := networkingv1.Ingress{}
+	if _, _, err := serializer.Decode(raw, nil, &ingress); err != nil {
+		validator.logger().Error("Failed to decode Ingress", "error", err.Error())
+		return ValidationResponse{}, err
+	}
+
+	validationResponse, err := validator.IsValidIngress(ingress)
+	if err != nil {
+		validator.logger().Error("Failed to validate Ingress", "error", err.Error())
+	}
+	return validationResponse, err
+}
+
+func validateTLSCertificateDelegation(validator Validator, raw []byte) (ValidationResponse, error) {
+	delegation := contourv1.TLSCertificateDelegation{}
+	if _, _, err := serializer.Decode(raw, nil, &delegation); err != nil {
+		validator.logger().Error("Failed to decode TLSCertificateDelegation", "error", err.Error())
+		return ValidationResponse{}, err
+	}
+
+	return validator.IsValidTLSCertificateDelegation(delegation)
+}
+
+func validateExtensionService(validator Validator, raw []byte) (ValidationResponse, error) {
+	extension := contourv1alpha1.ExtensionService{}
+	if _, _, err := serializer.Decode(raw, nil, &extension); err != nil {
+		validator.logger().Error("Failed to decode ExtensionService", "error", err.Error())
+		return ValidationResponse{}, err
+	}
+
+	return validator.IsValidExtensionService(extension)
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"log/slog"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	// "github.com/google/go-cmp/cmp/cmpopts"
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestHTTPProxyAdmissionHandler(t *testing.T) {
@@ -22,6 +27,7 @@ func TestHTTPProxyAdmissionHandler(t *testing.T) {
 		},
 	}
 	p1.SetName("proxy1")
+	p1.SetNamespace("default")
 
 	p2 := contourv1.HTTPProxy{
 		Spec: contourv1.HTTPProxySpec{
@@ -82,7 +88,7 @@ func TestHTTPProxyAdmissionHandler(t *testing.T) {
 		expectedResp admissionv1.AdmissionResponse
 	}{
 		{
-			"not review for HTTPProxy",
+			"review for unsupported resource",
 			&admissionv1.AdmissionReview{
 				Request: &admissionv1.AdmissionRequest{
 					Kind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
@@ -95,7 +101,7 @@ func TestHTTPProxyAdmissionHandler(t *testing.T) {
 					Status:  metav1.StatusFailure,
 					Code:    http.StatusBadRequest,
 					Reason:  metav1.StatusReasonBadRequest,
-					Message: "Review is not for HTTPProxy resource. Instead got apps/v1, Kind=Deployment with name my-deployment",
+					Message: "Review is for unsupported resource apps/v1, Kind=Deployment with name my-deployment",
 				},
 			},
 		},
@@ -152,6 +158,18 @@ func TestHTTPProxyAdmissionHandler(t *testing.T) {
 					Code:    http.StatusBadRequest,
 					Reason:  metav1.StatusReasonBadRequest,
 					Message: "proxy-new is in conflict with [proxy1]",
+					Details: &metav1.StatusDetails{
+						Name:  "proxy-new",
+						Group: "projectcontour.io",
+						Kind:  "HTTPProxy",
+						Causes: []metav1.StatusCause{
+							{
+								Type:    metav1.CauseTypeFieldValueInvalid,
+								Message: "fqdn-conflict: proxy-new is in conflict with [proxy1]",
+								Field:   "spec.virtualhost.fqdn",
+							},
+						},
+					},
 				},
 			},
 		},
@@ -185,10 +203,335 @@ func TestHTTPProxyAdmissionHandler(t *testing.T) {
 				Result:  nil,
 			},
 		},
+		{
+			"fail ingress validation",
+			&admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					Kind: metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+					Name: "ingress-new",
+					Object: runtime.RawExtension{
+						Raw: []byte(`
+{
+	"metadata": {
+		"name": "ingress-new"
+	},
+	"spec": {
+		"ingressClassName": "targetted",
+		"rules": [{"host": "foo.bar.com"}]
+	}
+}`),
+					},
+				},
+			},
+			admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusBadRequest,
+					Reason:  metav1.StatusReasonBadRequest,
+					Message: "ingress-new is in conflict with [httpproxy/default/proxy1]",
+					Details: &metav1.StatusDetails{
+						Name:  "ingress-new",
+						Group: "networking.k8s.io",
+						Kind:  "Ingress",
+						Causes: []metav1.StatusCause{
+							{
+								Type:    metav1.CauseTypeFieldValueInvalid,
+								Message: "hostname-conflict: ingress-new is in conflict with [httpproxy/default/proxy1]",
+								Field:   "spec.rules",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler.Validate(tt.review)
+
+			if tt.review.Response.Allowed != tt.expectedResp.Allowed {
+				t.Errorf("HTTPProxyAdmissionHandler.Validate %s: AdmissionResponse.Allowed got: %t,  want %t", tt.name, tt.review.Response.Allowed, tt.expectedResp.Allowed)
+			}
+
+			if diff := cmp.Diff(tt.review.Response.Result, tt.expectedResp.Result); diff != "" {
+				t.Errorf("HTTPProxyAdmissionHandler.Validate %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyAdmissionHandlerPolicy(t *testing.T) {
+	existing := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: "internal",
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "foo.bar.com",
+			},
+		},
+	}
+	existing.SetName("proxy1")
+
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return []contourv1.HTTPProxy{existing}, nil
+		},
+	}
+
+	policy := &HTTPProxyValidationPolicy{
+		Spec: HTTPProxyValidationPolicySpec{
+			TargetIngressClasses: []string{"internal"},
+		},
+	}
+	policy.SetName("default")
+	policy.SetGeneration(2)
+
+	handler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: store,
+		},
+		Policy: &TestPolicySource{policy},
+	}
+
+	newReview := func() *admissionv1.AdmissionReview {
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{Group: "projectcontour.io", Version: "v1", Kind: "HTTPProxy"},
+				Name: "proxy-new",
+				Object: runtime.RawExtension{
+					Raw: []byte(`{"metadata": {"name": "proxy-new"}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}, "ingressClassName": "internal"}}`),
+				},
+			},
+		}
+	}
+
+	expectedAnnotations := map[string]string{
+		"policy":            "default",
+		"policy-generation": "2",
+	}
+
+	review := newReview()
+	handler.Validate(review)
+
+	if review.Response.Allowed {
+		t.Errorf("expected review to be denied when policy targets the proxy ingress class")
+	}
+	if diff := cmp.Diff(review.Response.AuditAnnotations, expectedAnnotations); diff != "" {
+		t.Errorf("AuditAnnotations: (-got +want)\n%s", diff)
+	}
+
+	policy.Spec.EnforcementMode = EnforcementModeWarn
+	review = newReview()
+	handler.Validate(review)
+
+	if !review.Response.Allowed {
+		t.Errorf("expected review to be allowed in warn mode")
+	}
+	if diff := cmp.Diff(review.Response.Warnings, []string{"proxy-new is in conflict with [proxy1]"}); diff != "" {
+		t.Errorf("Warnings: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(review.Response.AuditAnnotations, expectedAnnotations); diff != "" {
+		t.Errorf("AuditAnnotations: (-got +want)\n%s", diff)
+	}
+}
+
+func TestHTTPProxyAdmissionHandlerKinds(t *testing.T) {
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, nil
+		},
+	}
+
+	tests := []struct {
+		name         string
+		kinds        []metav1.GroupVersionKind
+		review       *admissionv1.AdmissionReview
+		expectedResp admissionv1.AdmissionResponse
+	}{
+		{
+			"valid tls certificate delegation",
+			nil,
+			&admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					Kind: metav1.GroupVersionKind{Group: "projectcontour.io", Version: "v1", Kind: "TLSCertificateDelegation"},
+					Name: "delegation",
+					Object: runtime.RawExtension{
+						Raw: []byte(`{"metadata": {"name": "delegation"}, "spec": {"delegations": [{"secretName": "wildcard", "targetNamespaces": ["*"]}]}}`),
+					},
+				},
+			},
+			admissionv1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			"invalid extension service",
+			nil,
+			&admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					Kind: metav1.GroupVersionKind{Group: "projectcontour.io", Version: "v1alpha1", Kind: "ExtensionService"},
+					Name: "authz",
+					Object: runtime.RawExtension{
+						Raw: []byte(`{"metadata": {"name": "authz"}, "spec": {"services": [{"name": "authz", "port": 0}]}}`),
+					},
+				},
+			},
+			admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusBadRequest,
+					Reason:  metav1.StatusReasonBadRequest,
+					Message: "authz is invalid: services[0].port 0 is out of range",
+					Details: &metav1.StatusDetails{
+						Name:  "authz",
+						Group: "projectcontour.io",
+						Kind:  "ExtensionService",
+						Causes: []metav1.StatusCause{
+							{
+								Type:    metav1.CauseTypeFieldValueInvalid,
+								Message: "extension-service: services[0].port 0 is out of range",
+								Field:   "spec.services[0].port",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"kind not accepted by handler",
+			[]metav1.GroupVersionKind{httpProxyResource},
+			&admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					Kind: metav1.GroupVersionKind{Group: "projectcontour.io", Version: "v1alpha1", Kind: "ExtensionService"},
+					Name: "authz",
+					Object: runtime.RawExtension{
+						Raw: []byte(`{"metadata": {"name": "authz"}, "spec": {"services": [{"name": "authz", "port": 9443}]}}`),
+					},
+				},
+			},
+			admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusBadRequest,
+					Reason:  metav1.StatusReasonBadRequest,
+					Message: "Review is for unsupported resource projectcontour.io/v1alpha1, Kind=ExtensionService with name authz",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := HTTPProxyAdmissionHandler{
+				Validator: Validator{Store: store},
+				Kinds:     tt.kinds,
+			}
+			handler.Validate(tt.review)
+
+			if tt.review.Response.Allowed != tt.expectedResp.Allowed {
+				t.Errorf("HTTPProxyAdmissionHandler.Validate %s: AdmissionResponse.Allowed got: %t,  want %t", tt.name, tt.review.Response.Allowed, tt.expectedResp.Allowed)
+			}
+
+			if diff := cmp.Diff(tt.review.Response.Result, tt.expectedResp.Result); diff != "" {
+				t.Errorf("HTTPProxyAdmissionHandler.Validate %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyAdmissionHandlerDelete(t *testing.T) {
+	root := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "foo.bar.com",
+			},
+			Includes: []contourv1.Include{
+				{Name: "child"},
+			},
+		},
+	}
+	root.SetName("root")
+	root.SetNamespace("default")
+
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return []contourv1.HTTPProxy{root}, nil
+		},
+	}
+
+	newDeleteReview := func(kind metav1.GroupVersionKind, oldObject string) *admissionv1.AdmissionReview {
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Kind:      kind,
+				Name:      "child",
+				Namespace: "default",
+				Operation: admissionv1.Delete,
+				OldObject: runtime.RawExtension{
+					Raw: []byte(oldObject),
+				},
+			},
+		}
+	}
+
+	childProxy := `{"metadata": {"name": "child", "namespace": "default"}, "spec": {}}`
+
+	tests := []struct {
+		name         string
+		protect      bool
+		review       *admissionv1.AdmissionReview
+		expectedResp admissionv1.AdmissionResponse
+	}{
+		{
+			"delete allowed by default",
+			false,
+			newDeleteReview(httpProxyResource, childProxy),
+			admissionv1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			"delete of kind without delete validation",
+			true,
+			newDeleteReview(ingressResource, ""),
+			admissionv1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			"delete of included proxy",
+			true,
+			newDeleteReview(httpProxyResource, childProxy),
+			admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusBadRequest,
+					Reason:  metav1.StatusReasonBadRequest,
+					Message: "child is still included by [default/root], set the httpproxy-validation/force-delete annotation to delete it anyway",
+				},
+			},
+		},
+		{
+			"forced delete of included proxy",
+			true,
+			newDeleteReview(httpProxyResource, `{"metadata": {"name": "child", "namespace": "default", "annotations": {"httpproxy-validation/force-delete": "true"}}, "spec": {}}`),
+			admissionv1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			handler := HTTPProxyAdmissionHandler{
+				Validator: Validator{
+					Store:                  store,
+					ProtectIncludedProxies: tt.protect,
+				},
+			}
 			handler.Validate(tt.review)
 
 			if tt.review.Response.Allowed != tt.expectedResp.Allowed {
@@ -201,3 +544,296 @@ func TestHTTPProxyAdmissionHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPProxyAdmissionHandlerUpdate(t *testing.T) {
+	store := &MemoryStore{Proxies: []contourv1.HTTPProxy{
+		newTestProxy("team-a", "proxy", "foo.bar.com"),
+		newTestProxy("team-b", "proxy", "foo.bar.com"),
+	}}
+
+	newUpdateReview := func(oldObject, object string) *admissionv1.AdmissionReview {
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Kind:      httpProxyResource,
+				Name:      "proxy",
+				Namespace: "team-b",
+				Operation: admissionv1.Update,
+				OldObject: runtime.RawExtension{
+					Raw: []byte(oldObject),
+				},
+				Object: runtime.RawExtension{
+					Raw: []byte(object),
+				},
+			},
+		}
+	}
+
+	conflicting := `{"metadata": {"name": "proxy", "namespace": "team-b"}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}}}`
+
+	tests := []struct {
+		name            string
+		review          *admissionv1.AdmissionReview
+		expectedAllowed bool
+	}{
+		{
+			"conflict label set by the reconciler",
+			newUpdateReview(conflicting, `{"metadata": {"name": "proxy", "namespace": "team-b", "labels": {"httpproxy-validation/conflict": "true"}}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}}}`),
+			true,
+		},
+		{
+			"conflict annotation removed by the reconciler",
+			newUpdateReview(`{"metadata": {"name": "proxy", "namespace": "team-b", "annotations": {"httpproxy-validation/conflict": "true", "team": "b"}}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}}}`, `{"metadata": {"name": "proxy", "namespace": "team-b", "annotations": {"team": "b"}}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}}}`),
+			true,
+		},
+		{
+			"other labels are validated",
+			newUpdateReview(conflicting, `{"metadata": {"name": "proxy", "namespace": "team-b", "labels": {"httpproxy-validation/conflict": "true", "team": "b"}}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}}}`),
+			false,
+		},
+		{
+			"spec changes are validated",
+			newUpdateReview(conflicting, `{"metadata": {"name": "proxy", "namespace": "team-b"}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}, "routes": [{"services": [{"name": "app", "port": 80}]}]}}`),
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := HTTPProxyAdmissionHandler{
+				Validator: Validator{
+					Store:        store,
+					EnabledRules: []string{ruleFqdnConflict},
+				},
+			}
+			handler.Validate(tt.review)
+
+			if tt.review.Response.Allowed != tt.expectedAllowed {
+				t.Errorf("HTTPProxyAdmissionHandler.Validate %s: AdmissionResponse.Allowed got: %t,  want %t", tt.name, tt.review.Response.Allowed, tt.expectedAllowed)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyAdmissionHandlerTrafficShift(t *testing.T) {
+	blue := `{"metadata": {"name": "proxy", "namespace": "default"}, "spec": {"routes": [{"services": [{"name": "blue", "port": 80}]}]}}`
+	green := `{"metadata": {"name": "proxy", "namespace": "default"}, "spec": {"routes": [{"services": [{"name": "green", "port": 80}]}]}}`
+
+	tests := []struct {
+		name            string
+		operation       admissionv1.Operation
+		oldObject       string
+		expectedAllowed bool
+	}{
+		{"create", admissionv1.Create, "", true},
+		{"update", admissionv1.Update, blue, false},
+		{"update without a shift", admissionv1.Update, green, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := HTTPProxyAdmissionHandler{
+				Validator: Validator{
+					Store:        &MemoryStore{},
+					EnabledRules: []string{ruleServiceWeights},
+					Limits:       Limits{MaxTrafficShiftPercent: 25},
+				},
+			}
+			review := &admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					Kind:      httpProxyResource,
+					Name:      "proxy",
+					Namespace: "default",
+					Operation: tt.operation,
+					OldObject: runtime.RawExtension{
+						Raw: []byte(tt.oldObject),
+					},
+					Object: runtime.RawExtension{
+						Raw: []byte(green),
+					},
+				},
+			}
+			handler.Validate(review)
+
+			if review.Response.Allowed != tt.expectedAllowed {
+				t.Errorf("HTTPProxyAdmissionHandler.Validate %s: AdmissionResponse.Allowed got: %t,  want %t", tt.name, review.Response.Allowed, tt.expectedAllowed)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyAdmissionHandlerEvents(t *testing.T) {
+	existing := newTestProxy("team-a", "existing", "foo.bar.com")
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return []contourv1.HTTPProxy{existing}, nil
+		},
+	}
+
+	newReview := func(fqdn string) *admissionv1.AdmissionReview {
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Kind:      httpProxyResource,
+				Name:      "new",
+				Namespace: "team-b",
+				Operation: admissionv1.Create,
+				Object: runtime.RawExtension{
+					Raw: []byte(`{"metadata": {"name": "new", "namespace": "team-b"}, "spec": {"virtualhost": {"fqdn": "` + fqdn + `"}}}`),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		policy         *HTTPProxyValidationPolicy
+		review         *admissionv1.AdmissionReview
+		expectedEvents []string
+	}{
+		{
+			"denied conflict",
+			nil,
+			newReview("foo.bar.com"),
+			[]string{
+				"Warning ConflictingProxyDenied HTTPProxy team-b/new was denied claiming foo.bar.com, which is served by this proxy",
+			},
+		},
+		{
+			"allowed proxy",
+			nil,
+			newReview("foo.baz.com"),
+			nil,
+		},
+		{
+			"conflict allowed in warn mode",
+			&HTTPProxyValidationPolicy{
+				Spec: HTTPProxyValidationPolicySpec{
+					EnforcementMode: EnforcementModeWarn,
+				},
+			},
+			newReview("foo.bar.com"),
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(100)
+			handler := HTTPProxyAdmissionHandler{
+				Validator: Validator{
+					Store: store,
+				},
+				Policy: &TestPolicySource{tc.policy},
+				Events: NewDenialEvents(recorder, time.Minute),
+			}
+
+			handler.Validate(tc.review)
+
+			if diff := cmp.Diff(recordedEvents(recorder), tc.expectedEvents); diff != "" {
+				t.Errorf("unexpected events (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyAdmissionHandlerDecisionLog(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	existing := newTestProxy("team-a", "existing", "foo.bar.com")
+	handler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: &TestStore{
+				list: func() ([]contourv1.HTTPProxy, error) {
+					return []contourv1.HTTPProxy{existing}, nil
+				},
+			},
+			EnabledRules: []string{ruleFqdnConflict},
+		},
+	}
+
+	handler.Validate(&admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "1234",
+			Kind:      httpProxyResource,
+			Name:      "new",
+			Namespace: "team-b",
+			Operation: admissionv1.Create,
+			UserInfo: authenticationv1.UserInfo{
+				Username: "jane",
+				Groups:   []string{"team-b"},
+			},
+			Object: runtime.RawExtension{
+				Raw: []byte(`{"metadata": {"name": "new", "namespace": "team-b"}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}}}`),
+			},
+		},
+	})
+
+	records := decodeLogRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 log records, got %d: %v", len(records), records)
+	}
+	for _, record := range records {
+		if _, ok := record["latency"]; ok {
+			delete(record, "latency")
+		} else if record["msg"] == "Admission decision" {
+			t.Error("expected the decision to include the latency")
+		}
+	}
+
+	request := map[string]any{
+		"level":     "INFO",
+		"uid":       "1234",
+		"user":      "jane",
+		"groups":    []any{"team-b"},
+		"operation": "CREATE",
+		"kind":      "HTTPProxy",
+		"namespace": "team-b",
+		"name":      "new",
+	}
+	withRequest := func(attrs map[string]any) map[string]any {
+		for k, v := range request {
+			attrs[k] = v
+		}
+		return attrs
+	}
+
+	expected := []map[string]any{
+		withRequest(map[string]any{
+			"msg":               "Proxy validated",
+			"fqdn":              "foo.bar.com",
+			"ingressClass":      "",
+			"ingressClassMatch": true,
+			"rules": map[string]any{
+				ruleFqdnConflict:       "fail",
+				ruleFqdnOwnership:      "disabled",
+				ruleSharedFqdn:         "disabled",
+				ruleHostnameConflict:   "disabled",
+				ruleClusterConflict:    "disabled",
+				ruleServiceWeights:     "disabled",
+				ruleLoadBalancerPolicy: "disabled",
+				ruleHealthCheckPolicy:  "disabled",
+				ruleMatchConditions:    "disabled",
+				ruleTLSPolicy:          "disabled",
+				ruleAuthorization:      "disabled",
+				ruleJWTPolicy:          "disabled",
+				ruleCORSPolicy:         "disabled",
+				ruleRateLimitPolicy:    "disabled",
+				ruleHeaderPolicy:       "disabled",
+				ruleTimeoutPolicy:      "disabled",
+				ruleRetryPolicy:        "disabled",
+			},
+			"valid": false,
+		}),
+		withRequest(map[string]any{
+			"msg":      "Admission decision",
+			"allowed":  false,
+			"message":  "new is in conflict with [existing]",
+			"warnings": nil,
+		}),
+	}
+	if diff := cmp.Diff(records, expected); diff != "" {
+		t.Errorf("unexpected log records (-got +want):\n%s", diff)
+	}
+}
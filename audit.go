@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AuditReport lists the problems found across every HTTPProxy in a store.
+type AuditReport struct {
+	Conflicts       []ConflictGroup  `json:"conflicts"`
+	IncludeProblems []IncludeProblem `json:"includeProblems"`
+	Violations      []ProxyViolation `json:"violations"`
+}
+
+// ConflictGroup is a set of root proxies claiming the same fqdn.
+type ConflictGroup struct {
+	Fqdn    string       `json:"fqdn"`
+	Proxies []ProxyClass `json:"proxies"`
+}
+
+type ProxyClass struct {
+	Namespace    string `json:"namespace"`
+	Name         string `json:"name"`
+	IngressClass string `json:"ingressClass"`
+}
+
+type IncludeProblem struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Problem   string `json:"problem"`
+}
+
+type ProxyViolation struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Violation
+}
+
+func (r AuditReport) Problems() int {
+	return len(r.Conflicts) + len(r.IncludeProblems) + len(r.Violations)
+}
+
+// auditCommand reports existing problems in a cluster and returns the process
+// exit code.
+func auditCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var ingressClasses, kubeconfig, kubeContext, output string
+	fs.StringVar(&ingressClasses, "ingress-classes", "", "Comma separated list of ingress class names to validate against")
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig. Uses the default loading rules when empty")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context")
+	fs.StringVar(&output, "output", "table", "Report format, one of table or json")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	if output != "table" && output != outputJSON {
+		fmt.Fprintf(stderr, "Unknown output format %q\n", output)
+		return exitError
+	}
+
+	store, err := NewKubeconfigStore(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to setup cluster store: %s\n", err.Error())
+		return exitError
+	}
+
+	validator := Validator{
+		Store:                store,
+		TargetIngressClasses: splitList(ingressClasses),
+	}
+
+	report, err := validator.Audit()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to audit cluster: %s\n", err.Error())
+		return exitError
+	}
+
+	if output == outputJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = writeAuditTable(stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to write report: %s\n", err.Error())
+		return exitError
+	}
+
+	if report.Problems() > 0 {
+		return exitInvalid
+	}
+	return exitValid
+}
+
+// Audit takes a snapshot of the store and reports every fqdn conflict,
+// include problem and rule violation among the targetted proxies.
+func (v Validator) Audit() (AuditReport, error) {
+	report := AuditReport{
+		Conflicts:       []ConflictGroup{},
+		IncludeProblems: []IncludeProblem{},
+		Violations:      []ProxyViolation{},
+	}
+
+	all, err := v.listHTTPProxies()
+	if err != nil {
+		return report, err
+	}
+
+	var proxies []contourv1.HTTPProxy
+	for _, p := range all {
+		if v.proxyMatchesTargetIngressClasses(p) {
+			proxies = append(proxies, p)
+		}
+	}
+	sort.Slice(proxies, func(i, j int) bool {
+		return proxyKey(proxies[i]) < proxyKey(proxies[j])
+	})
+
+	report.Conflicts = v.fqdnConflictGroups(proxies)
+	report.IncludeProblems = includeProblems(proxies)
+
+	// Conflicts are already reported as groups, so every other rule is
+	// evaluated against the snapshot.
+	ruleValidator := v
+	ruleValidator.Store = snapshotStore{&MemoryStore{Proxies: proxies}, v.Store}
+	ruleValidator.EnabledRules = nil
+	for _, r := range rules {
+		if r.name != ruleFqdnConflict && v.ruleEnabled(r.name) {
+			ruleValidator.EnabledRules = append(ruleValidator.EnabledRules, r.name)
+		}
+	}
+	if len(ruleValidator.EnabledRules) == 0 {
+		return report, nil
+	}
+
+	for _, p := range proxies {
+		resp, err := ruleValidator.IsValidProxy(p)
+		if err != nil {
+			return report, err
+		}
+		for _, violation := range resp.Violations {
+			report.Violations = append(report.Violations, ProxyViolation{
+				Namespace: p.Namespace,
+				Name:      p.Name,
+				Violation: violation,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// normalizeFqdn lower cases the fqdn and strips the trailing dot of fully
+// qualified names so equivalent names are grouped together.
+func normalizeFqdn(fqdn string) string {
+	return strings.TrimSuffix(strings.ToLower(fqdn), ".")
+}
+
+func proxyKey(p contourv1.HTTPProxy) string {
+	return p.Namespace + "/" + p.Name
+}
+
+func (v Validator) fqdnConflictGroups(proxies []contourv1.HTTPProxy) []ConflictGroup {
+	groups := map[string][]contourv1.HTTPProxy{}
+	for _, p := range proxies {
+		if p.Spec.VirtualHost == nil {
+			continue
+		}
+		fqdn := normalizeFqdn(p.Spec.VirtualHost.Fqdn)
+		groups[fqdn] = append(groups[fqdn], p)
+	}
+
+	conflicts := []ConflictGroup{}
+	for fqdn, members := range groups {
+		if len(members) < 2 || v.allShareFqdn(members) {
+			continue
+		}
+
+		group := ConflictGroup{Fqdn: fqdn}
+		for _, p := range members {
+			group.Proxies = append(group.Proxies, ProxyClass{
+				Namespace:    p.Namespace,
+				Name:         p.Name,
+				IngressClass: proxyIngressClass(p),
+			})
+		}
+		conflicts = append(conflicts, group)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Fqdn < conflicts[j].Fqdn
+	})
+	return conflicts
+}
+
+// allShareFqdn reports whether every proxy opted into the same sharing group.
+func (v Validator) allShareFqdn(proxies []contourv1.HTTPProxy) bool {
+	for _, p := range proxies[1:] {
+		if !v.sharesFqdn(proxies[0], p) {
+			return false
+		}
+	}
+	return true
+}
+
+// includeProblems reports includes of missing or root proxies, include cycles
+// and non-root proxies that are not reachable from any root proxy.
+func includeProblems(proxies []contourv1.HTTPProxy) []IncludeProblem {
+	byKey := map[string]contourv1.HTTPProxy{}
+	for _, p := range proxies {
+		byKey[proxyKey(p)] = p
+	}
+
+	problems := []IncludeProblem{}
+	report := func(p contourv1.HTTPProxy, format string, args ...any) {
+		problems = append(problems, IncludeProblem{
+			Namespace: p.Namespace,
+			Name:      p.Name,
+			Problem:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	children := func(p contourv1.HTTPProxy) []string {
+		var keys []string
+		for _, include := range p.Spec.Includes {
+			namespace := include.Namespace
+			if namespace == "" {
+				namespace = p.Namespace
+			}
+			keys = append(keys, namespace+"/"+include.Name)
+		}
+		if tcp := p.Spec.TCPProxy; tcp != nil {
+			for _, include := range []*contourv1.TCPProxyInclude{tcp.Include, tcp.IncludesDeprecated} {
+				if include == nil {
+					continue
+				}
+				namespace := include.Namespace
+				if namespace == "" {
+					namespace = p.Namespace
+				}
+				keys = append(keys, namespace+"/"+include.Name)
+			}
+		}
+		return keys
+	}
+
+	for _, p := range proxies {
+		for _, key := range children(p) {
+			child, ok := byKey[key]
+			switch {
+			case !ok:
+				report(p, "includes missing proxy %s", key)
+			case child.Spec.VirtualHost != nil:
+				report(p, "includes root proxy %s", key)
+			}
+		}
+	}
+
+	reachable := map[string]bool{}
+	var visit func(p contourv1.HTTPProxy, path []string)
+	visit = func(p contourv1.HTTPProxy, path []string) {
+		key := proxyKey(p)
+		if i := slices.Index(path, key); i >= 0 {
+			report(p, "include cycle %s", strings.Join(append(path[i:], key), " -> "))
+			return
+		}
+		if reachable[key] && len(path) > 0 {
+			return
+		}
+		reachable[key] = true
+
+		for _, childKey := range children(p) {
+			if child, ok := byKey[childKey]; ok && child.Spec.VirtualHost == nil {
+				visit(child, append(path, key))
+			}
+		}
+	}
+
+	for _, p := range proxies {
+		if p.Spec.VirtualHost != nil {
+			visit(p, nil)
+		}
+	}
+
+	for _, p := range proxies {
+		if p.Spec.VirtualHost == nil && !reachable[proxyKey(p)] {
+			report(p, "not included by any root proxy")
+		}
+	}
+
+	return problems
+}
+
+func writeAuditTable(w io.Writer, report AuditReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintln(tw, "FQDN\tPROXIES")
+		for _, group := range report.Conflicts {
+			var members []string
+			for _, p := range group.Proxies {
+				member := p.Namespace + "/" + p.Name
+				if p.IngressClass != "" {
+					member += " (" + p.IngressClass + ")"
+				}
+				members = append(members, member)
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", group.Fqdn, strings.Join(members, ", "))
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if len(report.IncludeProblems) > 0 {
+		fmt.Fprintln(tw, "PROXY\tINCLUDE PROBLEM")
+		for _, problem := range report.IncludeProblems {
+			fmt.Fprintf(tw, "%s/%s\t%s\n", problem.Namespace, problem.Name, problem.Problem)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if len(report.Violations) > 0 {
+		fmt.Fprintln(tw, "PROXY\tRULE\tFIELD\tMESSAGE")
+		for _, violation := range report.Violations {
+			fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\n", violation.Namespace, violation.Name, violation.Rule, violation.Field, violation.Message)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	fmt.Fprintf(tw, "%d conflicts, %d include problems, %d rule violations\n", len(report.Conflicts), len(report.IncludeProblems), len(report.Violations))
+	return tw.Flush()
+}
+
+// snapshotStore serves the proxies of an audit snapshot and looks up
+// Namespaces, Services and ExtensionServices in the store the snapshot was
+// taken from.
+type snapshotStore struct {
+	*MemoryStore
+	base Store
+}
+
+func (s snapshotStore) GetNamespace(name string) (*corev1.Namespace, error) {
+	if namespaceStore, ok := s.base.(NamespaceStore); ok {
+		return namespaceStore.GetNamespace(name)
+	}
+	return nil, nil
+}
+
+func (s snapshotStore) GetService(namespace, name string) (*corev1.Service, error) {
+	if serviceStore, ok := s.base.(ServiceStore); ok {
+		return serviceStore.GetService(namespace, name)
+	}
+	return nil, nil
+}
+
+func (s snapshotStore) GetExtensionService(namespace, name string) (*contourv1alpha1.ExtensionService, error) {
+	if extensionStore, ok := s.base.(ExtensionServiceStore); ok {
+		return extensionStore.GetExtensionService(namespace, name)
+	}
+	return nil, nil
+}
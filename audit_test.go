@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func newTestProxy(namespace, name, fqdn string, includes ...string) contourv1.HTTPProxy {
+	p := contourv1.HTTPProxy{}
+	p.SetNamespace(namespace)
+	p.SetName(name)
+	if fqdn != "" {
+		p.Spec.VirtualHost = &contourv1.VirtualHost{Fqdn: fqdn}
+	}
+	for _, include := range includes {
+		p.Spec.Includes = append(p.Spec.Includes, contourv1.Include{Name: include})
+	}
+	return p
+}
+
+func TestAudit(t *testing.T) {
+	other := newTestProxy("default", "other-class", "foo.bar.com")
+	other.Spec.IngressClassName = "not-targetted"
+
+	tests := []struct {
+		name           string
+		validator      Validator
+		proxies        []contourv1.HTTPProxy
+		expectedReport AuditReport
+	}{
+		{
+			"no problems",
+			Validator{},
+			[]contourv1.HTTPProxy{
+				newTestProxy("default", "root", "foo.bar.com", "child"),
+				newTestProxy("default", "child", ""),
+			},
+			AuditReport{
+				Conflicts:       []ConflictGroup{},
+				IncludeProblems: []IncludeProblem{},
+				Violations:      []ProxyViolation{},
+			},
+		},
+		{
+			"fqdn conflicts are grouped after normalizing",
+			Validator{},
+			[]contourv1.HTTPProxy{
+				newTestProxy("team-b", "proxy", "FOO.bar.com."),
+				newTestProxy("team-a", "proxy", "foo.bar.com"),
+				newTestProxy("team-a", "unique", "foo.baz.com"),
+				other,
+			},
+			AuditReport{
+				Conflicts: []ConflictGroup{
+					{
+						Fqdn: "foo.bar.com",
+						Proxies: []ProxyClass{
+							{Namespace: "team-a", Name: "proxy"},
+							{Namespace: "team-b", Name: "proxy"},
+						},
+					},
+				},
+				IncludeProblems: []IncludeProblem{},
+				Violations:      []ProxyViolation{},
+			},
+		},
+		{
+			"include problems",
+			Validator{},
+			[]contourv1.HTTPProxy{
+				newTestProxy("default", "root", "foo.bar.com", "missing", "other-root", "a"),
+				newTestProxy("default", "other-root", "foo.baz.com"),
+				newTestProxy("default", "a", "", "b"),
+				newTestProxy("default", "b", "", "a"),
+				newTestProxy("default", "orphan", ""),
+			},
+			AuditReport{
+				Conflicts: []ConflictGroup{},
+				IncludeProblems: []IncludeProblem{
+					{Namespace: "default", Name: "root", Problem: "includes missing proxy default/missing"},
+					{Namespace: "default", Name: "root", Problem: "includes root proxy default/other-root"},
+					{Namespace: "default", Name: "a", Problem: "include cycle default/a -> default/b -> default/a"},
+					{Namespace: "default", Name: "orphan", Problem: "not included by any root proxy"},
+				},
+				Violations: []ProxyViolation{},
+			},
+		},
+		{
+			"rule violations",
+			Validator{
+				FqdnOwnership: []FqdnOwnership{
+					{Fqdn: "*.bar.com", Namespaces: []string{"team-a"}},
+				},
+			},
+			[]contourv1.HTTPProxy{
+				newTestProxy("team-a", "allowed", "foo.bar.com"),
+				newTestProxy("team-b", "denied", "baz.bar.com"),
+			},
+			AuditReport{
+				Conflicts:       []ConflictGroup{},
+				IncludeProblems: []IncludeProblem{},
+				Violations: []ProxyViolation{
+					{
+						Namespace: "team-b",
+						Name:      "denied",
+						Violation: Violation{
+							Rule:    ruleFqdnOwnership,
+							Field:   "spec.virtualhost.fqdn",
+							Message: `namespace "team-b" is not allowed to claim baz.bar.com`,
+						},
+					},
+				},
+			},
+		},
+		{
+			"disabled rules are not audited",
+			Validator{
+				EnabledRules: []string{ruleFqdnConflict},
+				FqdnOwnership: []FqdnOwnership{
+					{Fqdn: "*.bar.com", Namespaces: []string{"team-a"}},
+				},
+			},
+			[]contourv1.HTTPProxy{
+				newTestProxy("team-b", "denied", "baz.bar.com"),
+			},
+			AuditReport{
+				Conflicts:       []ConflictGroup{},
+				IncludeProblems: []IncludeProblem{},
+				Violations:      []ProxyViolation{},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			proxies := tc.proxies
+			tc.validator.Store = &TestStore{
+				list: func() ([]contourv1.HTTPProxy, error) {
+					return proxies, nil
+				},
+			}
+
+			report, err := tc.validator.Audit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(report, tc.expectedReport); diff != "" {
+				t.Errorf("unexpected report (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuditStoreError(t *testing.T) {
+	validator := Validator{
+		Store: &TestStore{
+			list: func() ([]contourv1.HTTPProxy, error) {
+				return nil, errors.New("store error")
+			},
+		},
+	}
+
+	if _, err := validator.Audit(); err == nil {
+		t.Error("expected store error")
+	}
+}
+
+func TestWriteAuditTable(t *testing.T) {
+	report := AuditReport{
+		Conflicts: []ConflictGroup{
+			{
+				Fqdn: "foo.bar.com",
+				Proxies: []ProxyClass{
+					{Namespace: "team-a", Name: "proxy", IngressClass: "contour"},
+					{Namespace: "team-b", Name: "proxy"},
+				},
+			},
+		},
+		IncludeProblems: []IncludeProblem{
+			{Namespace: "default", Name: "orphan", Problem: "not included by any root proxy"},
+		},
+		Violations: []ProxyViolation{
+			{
+				Namespace: "team-b",
+				Name:      "denied",
+				Violation: Violation{Rule: ruleFqdnOwnership, Field: "spec.virtualhost.fqdn", Message: "not allowed"},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := writeAuditTable(&out, report); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `FQDN         PROXIES
+foo.bar.com  team-a/proxy (contour), team-b/proxy
+
+PROXY           INCLUDE PROBLEM
+default/orphan  not included by any root proxy
+
+PROXY          RULE            FIELD                  MESSAGE
+team-b/denied  fqdn-ownership  spec.virtualhost.fqdn  not allowed
+
+1 conflicts, 1 include problems, 1 rule violations
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("unexpected table (-got +want):\n%s", diff)
+	}
+}
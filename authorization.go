@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const ruleAuthorization = "authorization"
+
+func (v Validator) checkAuthorization(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+	lookupFailed := func(err error) (ValidationResponse, error) {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: "could not list resources",
+		}, err
+	}
+
+	// Disabling authorization is only looked up once a policy disables it.
+	var forbidden *bool
+	checkDisabled := func(field string, policy *contourv1.AuthorizationPolicy) error {
+		if policy == nil || !policy.Disabled {
+			return nil
+		}
+		if forbidden == nil {
+			selected, err := v.namespaceSelected(v.Authorization.ForbidDisablingNamespaceSelector, proxy.Namespace)
+			if err != nil {
+				return err
+			}
+			forbidden = &selected
+		}
+		if *forbidden {
+			invalid(field, "%s: namespace %q may not disable authorization", field, proxy.Namespace)
+		}
+		return nil
+	}
+
+	if vhost := proxy.Spec.VirtualHost; vhost != nil && vhost.Authorization != nil {
+		if vhost.TLS == nil || vhost.TLS.SecretName == "" {
+			invalid("virtualhost.authorization", "virtualhost.authorization requires a virtual host that terminates TLS")
+		}
+
+		ref := vhost.Authorization.ExtensionServiceRef
+		if ref.Name == "" {
+			invalid("virtualhost.authorization.extensionRef.name", "virtualhost.authorization.extensionRef.name is required")
+		} else {
+			namespace := ref.Namespace
+			if namespace == "" {
+				namespace = proxy.Namespace
+			}
+			extension, ok, err := v.getExtensionService(namespace, ref.Name)
+			if err != nil {
+				return lookupFailed(err)
+			}
+			if ok && extension == nil {
+				invalid("virtualhost.authorization.extensionRef", "virtualhost.authorization.extensionRef: extension service %s/%s does not exist", namespace, ref.Name)
+			}
+		}
+
+		if err := checkDisabled("virtualhost.authorization.authPolicy.disabled", vhost.Authorization.AuthPolicy); err != nil {
+			return lookupFailed(err)
+		}
+	}
+
+	for i, route := range proxy.Spec.Routes {
+		if err := checkDisabled(fmt.Sprintf("routes[%d].authPolicy.disabled", i), route.AuthPolicy); err != nil {
+			return lookupFailed(err)
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
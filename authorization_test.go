@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsValidProxyAuthorization(t *testing.T) {
+	store := &MemoryStore{
+		Namespaces: []corev1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"auth": "required"}}},
+		},
+		ExtensionServices: []contourv1alpha1.ExtensionService{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "auth", Name: "authz"}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "local-authz"}},
+		},
+	}
+	restrictions := AuthorizationRestrictions{
+		ForbidDisablingNamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"auth": "required"},
+		},
+	}
+	tls := &contourv1.TLS{SecretName: "tls"}
+
+	tests := []struct {
+		name          string
+		namespace     string
+		tls           *contourv1.TLS
+		authorization *contourv1.AuthorizationServer
+		routePolicy   *contourv1.AuthorizationPolicy
+		expected      ValidationResponse
+	}{
+		{
+			"no authorization",
+			"default",
+			nil,
+			nil,
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"valid authorization",
+			"payments",
+			tls,
+			&contourv1.AuthorizationServer{
+				ExtensionServiceRef: contourv1.ExtensionServiceReference{Namespace: "auth", Name: "authz"},
+			},
+			&contourv1.AuthorizationPolicy{Context: map[string]string{"route": "checkout"}},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"extension service in the proxy namespace",
+			"default",
+			tls,
+			&contourv1.AuthorizationServer{
+				ExtensionServiceRef: contourv1.ExtensionServiceReference{Name: "local-authz"},
+			},
+			&contourv1.AuthorizationPolicy{Disabled: true},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"missing extension service without tls",
+			"default",
+			nil,
+			&contourv1.AuthorizationServer{
+				ExtensionServiceRef: contourv1.ExtensionServiceReference{Namespace: "auth", Name: "authz-typo"},
+			},
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.authorization requires a virtual host that terminates TLS; virtualhost.authorization.extensionRef: extension service auth/authz-typo does not exist",
+				Violations: []Violation{
+					{Rule: ruleAuthorization, Field: "spec.virtualhost.authorization", Message: "virtualhost.authorization requires a virtual host that terminates TLS"},
+					{Rule: ruleAuthorization, Field: "spec.virtualhost.authorization.extensionRef", Message: "virtualhost.authorization.extensionRef: extension service auth/authz-typo does not exist"},
+				},
+			},
+		},
+		{
+			"disabled in a namespace requiring authorization",
+			"payments",
+			tls,
+			&contourv1.AuthorizationServer{
+				AuthPolicy: &contourv1.AuthorizationPolicy{Disabled: true},
+			},
+			&contourv1.AuthorizationPolicy{Disabled: true},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: virtualhost.authorization.extensionRef.name is required; virtualhost.authorization.authPolicy.disabled: namespace "payments" may not disable authorization; routes[0].authPolicy.disabled: namespace "payments" may not disable authorization`,
+				Violations: []Violation{
+					{Rule: ruleAuthorization, Field: "spec.virtualhost.authorization.extensionRef.name", Message: "virtualhost.authorization.extensionRef.name is required"},
+					{Rule: ruleAuthorization, Field: "spec.virtualhost.authorization.authPolicy.disabled", Message: `virtualhost.authorization.authPolicy.disabled: namespace "payments" may not disable authorization`},
+					{Rule: ruleAuthorization, Field: "spec.routes[0].authPolicy.disabled", Message: `routes[0].authPolicy.disabled: namespace "payments" may not disable authorization`},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:         store,
+				EnabledRules:  []string{ruleAuthorization},
+				Authorization: restrictions,
+			}
+
+			proxy := newTestProxy(tc.namespace, "proxy-under-test", "foo.bar.com")
+			proxy.Spec.VirtualHost.TLS = tc.tls
+			proxy.Spec.VirtualHost.Authorization = tc.authorization
+			proxy.Spec.Routes = []contourv1.Route{{AuthPolicy: tc.routePolicy}}
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyAuthorizationLookupUnsupported(t *testing.T) {
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, nil
+		},
+	}
+
+	validator := Validator{
+		Store:        store,
+		EnabledRules: []string{ruleAuthorization},
+	}
+
+	proxy := newRouteProxy()
+	proxy.Spec.VirtualHost.TLS = &contourv1.TLS{SecretName: "tls"}
+	proxy.Spec.VirtualHost.Authorization = &contourv1.AuthorizationServer{
+		ExtensionServiceRef: contourv1.ExtensionServiceReference{Namespace: "auth", Name: "authz"},
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(resp, ValidationResponse{Valid: true}); diff != "" {
+		t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+	}
+}
+
+func TestIsValidProxyAuthorizationIncludedRoutes(t *testing.T) {
+	validator := Validator{
+		Store: &MemoryStore{
+			Namespaces: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"auth": "required"}}},
+			},
+		},
+		EnabledRules: []string{ruleAuthorization},
+		Authorization: AuthorizationRestrictions{
+			ForbidDisablingNamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"auth": "required"},
+			},
+		},
+	}
+
+	proxy := newTestProxy("payments", "child", "")
+	proxy.Spec.Routes = []contourv1.Route{
+		{},
+		{AuthPolicy: &contourv1.AuthorizationPolicy{Disabled: true}},
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ValidationResponse{
+		Valid:  false,
+		Reason: `child is invalid: routes[1].authPolicy.disabled: namespace "payments" may not disable authorization`,
+		Violations: []Violation{
+			{Rule: ruleAuthorization, Field: "spec.routes[1].authPolicy.disabled", Message: `routes[1].authPolicy.disabled: namespace "payments" may not disable authorization`},
+		},
+	}
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"regexp"
+	"sync"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+const redactedValue = "REDACTED"
+
+// lastAppliedAnnotation holds a copy of the object as last applied by
+// kubectl, which is redacted like the object itself.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// ReviewRecorder is called by AdmissionMiddleware with each review once its
+// response has been set.
+type ReviewRecorder interface {
+	Record(*admissionv1.AdmissionReview)
+}
+
+// Capture records sampled AdmissionReviews, including their responses, as
+// JSON lines that can be replayed with ReplayReviews.
+type Capture struct {
+	// SampleRate is the fraction of reviews recorded, between 0 and 1.
+	SampleRate float64
+	// Redact matches the keys of fields in the request objects whose values
+	// are replaced before recording, such as annotations holding secrets.
+	Redact []*regexp.Regexp
+
+	mu     sync.Mutex
+	w      io.Writer
+	sample func() float64
+}
+
+func NewCapture(w io.Writer, sampleRate float64, redact []*regexp.Regexp) *Capture {
+	return &Capture{
+		SampleRate: sampleRate,
+		Redact:     redact,
+		w:          w,
+		sample:     rand.Float64,
+	}
+}
+
+func (c *Capture) Record(review *admissionv1.AdmissionReview) {
+	if c.SampleRate <= 0 || (c.SampleRate < 1 && c.sample() >= c.SampleRate) {
+		return
+	}
+
+	data, err := c.encode(review)
+	if err != nil {
+		slog.Error("Failed to encode captured review", "error", err.Error())
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.w.Write(append(data, '\n')); err != nil {
+		slog.Error("Failed to write captured review", "error", err.Error())
+	}
+}
+
+// encode marshals the review to a single line, redacting the request objects
+// without modifying the review sent back to the API server.
+func (c *Capture) encode(review *admissionv1.AdmissionReview) ([]byte, error) {
+	data, err := json.Marshal(review)
+	if err != nil || len(c.Redact) == 0 {
+		return data, err
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if req, ok := doc["request"].(map[string]any); ok {
+		for _, field := range []string{"object", "oldObject"} {
+			if obj, ok := req[field]; ok {
+				c.redact(obj)
+			}
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func (c *Capture) redact(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			switch applied, isString := child.(string); {
+			case c.redactKey(key):
+				v[key] = redactedValue
+			case key == lastAppliedAnnotation && isString:
+				v[key] = c.redactLastApplied(applied)
+			default:
+				c.redact(child)
+			}
+		}
+	case []any:
+		for _, child := range v {
+			c.redact(child)
+		}
+	}
+}
+
+// redactLastApplied redacts the object encoded in the last applied
+// configuration, dropping it entirely when it cannot be decoded.
+func (c *Capture) redactLastApplied(applied string) string {
+	var obj any
+	if err := json.Unmarshal([]byte(applied), &obj); err != nil {
+		return redactedValue
+	}
+	c.redact(obj)
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return redactedValue
+	}
+	return string(data)
+}
+
+func (c *Capture) redactKey(key string) bool {
+	for _, pattern := range c.Redact {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RotatingFile is a writer that renames the file to path.1, path.2 and so on
+// once it reaches MaxSize bytes, keeping at most MaxFiles rotated files.
+type RotatingFile struct {
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func NewRotatingFile(path string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	r := &RotatingFile{
+		Path:     path,
+		MaxSize:  maxSize,
+		MaxFiles: maxFiles,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+
+	if r.MaxFiles > 0 {
+		for i := r.MaxFiles - 1; i > 0; i-- {
+			err := os.Rename(fmt.Sprintf("%s.%d", r.Path, i), fmt.Sprintf("%s.%d", r.Path, i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(r.Path, r.Path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.Path); err != nil {
+		return err
+	}
+
+	return r.open()
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
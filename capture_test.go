@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newCaptureReview(object string) *admissionv1.AdmissionReview {
+	review := &admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "1",
+			Kind:      httpProxyResource,
+			Name:      "proxy",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: []byte(object)},
+		},
+		Response: &admissionv1.AdmissionResponse{
+			UID:     "1",
+			Allowed: true,
+		},
+	}
+	review.SetGroupVersionKind(admissionv1.SchemeGroupVersion.WithKind("AdmissionReview"))
+	return review
+}
+
+func TestCaptureRecord(t *testing.T) {
+	object := `{"metadata":{"name":"proxy","annotations":{"example.com/token":"secret","other":"value"}},"spec":{"routes":[{"requestHeadersPolicy":{"set":[{"name":"x","value":"y"}]}}]}}`
+
+	tests := []struct {
+		name        string
+		sampleRate  float64
+		sample      float64
+		redact      []*regexp.Regexp
+		expectedOut string
+	}{
+		{
+			"records the request and response",
+			1,
+			0.99,
+			nil,
+			`{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","request":{"uid":"1","kind":{"group":"projectcontour.io","version":"v1","kind":"HTTPProxy"},"resource":{"group":"","version":"","resource":""},"name":"proxy","operation":"CREATE","userInfo":{},"object":` + object + `,"oldObject":null,"options":null},"response":{"uid":"1","allowed":true}}` + "\n",
+		},
+		{
+			"redacts matching keys",
+			1,
+			0,
+			[]*regexp.Regexp{regexp.MustCompile(`token$`), regexp.MustCompile(`^requestHeadersPolicy$`)},
+			`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"kind":{"group":"projectcontour.io","kind":"HTTPProxy","version":"v1"},"name":"proxy","object":{"metadata":{"annotations":{"example.com/token":"REDACTED","other":"value"},"name":"proxy"},"spec":{"routes":[{"requestHeadersPolicy":"REDACTED"}]}},"oldObject":null,"operation":"CREATE","options":null,"resource":{"group":"","resource":"","version":""},"uid":"1","userInfo":{}},"response":{"allowed":true,"uid":"1"}}` + "\n",
+		},
+		{
+			"sampled in",
+			0.5,
+			0.25,
+			nil,
+			`{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","request":{"uid":"1","kind":{"group":"projectcontour.io","version":"v1","kind":"HTTPProxy"},"resource":{"group":"","version":"","resource":""},"name":"proxy","operation":"CREATE","userInfo":{},"object":` + object + `,"oldObject":null,"options":null},"response":{"uid":"1","allowed":true}}` + "\n",
+		},
+		{
+			"sampled out",
+			0.5,
+			0.75,
+			nil,
+			"",
+		},
+		{
+			"disabled",
+			0,
+			0,
+			nil,
+			"",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			capture := NewCapture(&out, tc.sampleRate, tc.redact)
+			capture.sample = func() float64 { return tc.sample }
+
+			review := newCaptureReview(object)
+			capture.Record(review)
+
+			if diff := cmp.Diff(out.String(), tc.expectedOut); diff != "" {
+				t.Errorf("unexpected capture (-got +want):\n%s", diff)
+			}
+			if diff := cmp.Diff(string(review.Request.Object.Raw), object); diff != "" {
+				t.Errorf("review was modified (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCaptureRedactLastApplied(t *testing.T) {
+	tests := []struct {
+		name     string
+		applied  string
+		expected string
+	}{
+		{
+			"redacts the applied object",
+			`{"metadata":{"annotations":{"example.com/token":"secret"},"name":"proxy"}}`,
+			`{"metadata":{"annotations":{"example.com/token":"REDACTED"},"name":"proxy"}}`,
+		},
+		{
+			"drops an applied object that cannot be decoded",
+			`{"example.com/token":"secret"`,
+			redactedValue,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			object, err := json.Marshal(map[string]any{
+				"metadata": map[string]any{
+					"name": "proxy",
+					"annotations": map[string]string{
+						"example.com/token":   "secret",
+						lastAppliedAnnotation: tc.applied,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			capture := NewCapture(&out, 1, []*regexp.Regexp{regexp.MustCompile(`token$`)})
+			capture.Record(newCaptureReview(string(object)))
+
+			if strings.Contains(out.String(), "secret") {
+				t.Errorf("capture contains a redacted value: %s", out.String())
+			}
+
+			var captured struct {
+				Request struct {
+					Object struct {
+						Metadata struct {
+							Annotations map[string]string `json:"annotations"`
+						} `json:"metadata"`
+					} `json:"object"`
+				} `json:"request"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &captured); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(captured.Request.Object.Metadata.Annotations[lastAppliedAnnotation], tc.expected); diff != "" {
+				t.Errorf("unexpected last applied configuration (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAdmissionMiddlewareCapture(t *testing.T) {
+	handler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: replaySnapshotStore(t),
+		},
+	}
+
+	var captured bytes.Buffer
+	capture := NewCapture(&captured, 1, nil)
+	middleware := AdmissionMiddleware(handler.ValidateContext, capture)
+
+	reviews, err := os.ReadFile("testdata/replay/reviews.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(reviews)), "\n") {
+		review := admissionv1.AdmissionReview{}
+		if _, _, err := serializer.Decode([]byte(line), nil, &review); err != nil {
+			t.Fatal(err)
+		}
+		review.Response = nil
+
+		var body bytes.Buffer
+		if err := serializer.Encode(&review, &body); err != nil {
+			t.Fatal(err)
+		}
+		recorder := httptest.NewRecorder()
+		middleware.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", &body))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d", recorder.Code)
+		}
+	}
+
+	// Captured traffic replays against the same snapshot without differences.
+	results, err := ReplayReviews(&captured, AdmissionMiddleware(handler.ValidateContext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 captured reviews, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Recorded == nil {
+			t.Errorf("line %d: response was not captured", result.Line)
+		}
+		if diff := result.Diff(); diff != "" {
+			t.Errorf("line %d changed (-recorded +replayed):\n%s", result.Line, diff)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	file, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(data), content); diff != "" {
+			t.Errorf("unexpected content of %s (-got +want):\n%s", name, diff)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 rotated files, got %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const ruleMatchConditions = "match-conditions"
+
+// conditionProblem is a problem with the condition at index of a condition
+// block. The message does not refer to the index, so problems of combined
+// blocks can be compared.
+type conditionProblem struct {
+	index   int
+	message string
+}
+
+// includeChain lists the conditions a proxy inherits from a root proxy
+// through its includes.
+type includeChain struct {
+	root       string
+	conditions []contourv1.MatchCondition
+}
+
+var duplicateSlashes = regexp.MustCompile(`//+`)
+
+// pathConditionProblems follows contour: a block has at most one prefix,
+// exact or regex condition, starting with /, and includes only use prefixes.
+func pathConditionProblems(conds []contourv1.MatchCondition, include bool) []conditionProblem {
+	var problems []conditionProblem
+	paths := 0
+	for i, cond := range conds {
+		for _, path := range []struct{ kind, value string }{{"prefix", cond.Prefix}, {"exact", cond.Exact}, {"regex", cond.Regex}} {
+			if path.value == "" {
+				continue
+			}
+			paths++
+			switch {
+			case paths > 1:
+				problems = append(problems, conditionProblem{i, "more than one prefix, exact or regex is not allowed in a condition block"})
+			case include && path.kind != "prefix":
+				problems = append(problems, conditionProblem{i, fmt.Sprintf("%s conditions are not allowed in includes", path.kind)})
+			case path.value[0] != '/':
+				problems = append(problems, conditionProblem{i, fmt.Sprintf("%s %q must start with /", path.kind, path.value)})
+			case path.kind == "regex":
+				if _, err := regexp.Compile(path.value); err != nil {
+					problems = append(problems, conditionProblem{i, fmt.Sprintf("regex %q is not a valid regex: %s", path.value, err)})
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// headerConditionProblems follows contour, also requiring a single match per
+// header condition.
+func headerConditionProblems(conds []contourv1.MatchCondition) []conditionProblem {
+	var problems []conditionProblem
+	seen := map[contourv1.HeaderMatchCondition]bool{}
+	exact := map[string]bool{}
+	for i, cond := range conds {
+		header := cond.Header
+		if header == nil {
+			continue
+		}
+		problem := func(format string, args ...any) {
+			problems = append(problems, conditionProblem{i, fmt.Sprintf("header %q ", header.Name) + fmt.Sprintf(format, args...)})
+		}
+		contradicts := func(kind, other string, key contourv1.HeaderMatchCondition) {
+			key.Name = strings.ToLower(header.Name)
+			if seen[key] {
+				problem("has contradictory %s and %s conditions", other, kind)
+			}
+		}
+
+		name := strings.ToLower(header.Name)
+		matches := 0
+		for _, set := range []bool{header.Present, header.NotPresent, header.Contains != "", header.NotContains != "", header.Exact != "", header.NotExact != "", header.Regex != ""} {
+			if set {
+				matches++
+			}
+		}
+		switch {
+		case len(validation.IsHTTPHeaderName(header.Name)) > 0:
+			problem("is not a valid header name")
+		case matches != 1:
+			problem("must set exactly one match")
+		case header.Present:
+			contradicts("present", "notpresent", contourv1.HeaderMatchCondition{NotPresent: true})
+		case header.NotPresent:
+			contradicts("notpresent", "present", contourv1.HeaderMatchCondition{Present: true})
+		case header.Exact != "":
+			if exact[name] {
+				problem("has more than one exact condition")
+			}
+			exact[name] = true
+			contradicts("exact", "notexact", contourv1.HeaderMatchCondition{NotExact: header.Exact})
+		case header.NotExact != "":
+			contradicts("notexact", "exact", contourv1.HeaderMatchCondition{Exact: header.NotExact})
+		case header.Contains != "":
+			contradicts("contains", "notcontains", contourv1.HeaderMatchCondition{NotContains: header.Contains})
+		case header.NotContains != "":
+			contradicts("notcontains", "contains", contourv1.HeaderMatchCondition{Contains: header.NotContains})
+		case header.Regex != "":
+			if _, err := regexp.Compile(header.Regex); err != nil {
+				problem("regex %q is not a valid regex: %s", header.Regex, err)
+			}
+		}
+
+		key := *header
+		key.Name = name
+		seen[key] = true
+	}
+	return problems
+}
+
+func queryConditionProblems(conds []contourv1.MatchCondition) []conditionProblem {
+	var problems []conditionProblem
+	exact := map[string]bool{}
+	for i, cond := range conds {
+		query := cond.QueryParameter
+		if query == nil {
+			continue
+		}
+		problem := func(format string, args ...any) {
+			problems = append(problems, conditionProblem{i, fmt.Sprintf("query parameter %q ", query.Name) + fmt.Sprintf(format, args...)})
+		}
+
+		matches := 0
+		for _, set := range []bool{query.Present, query.Exact != "", query.Prefix != "", query.Suffix != "", query.Regex != "", query.Contains != ""} {
+			if set {
+				matches++
+			}
+		}
+		switch {
+		case matches != 1:
+			problem("must set exactly one match")
+		case query.Exact != "":
+			name := strings.ToLower(query.Name)
+			if exact[name] {
+				problem("has more than one exact condition")
+			}
+			exact[name] = true
+		case query.Regex != "":
+			if _, err := regexp.Compile(query.Regex); err != nil {
+				problem("regex %q is not a valid regex: %s", query.Regex, err)
+			}
+		}
+	}
+	return problems
+}
+
+// combinedConditionProblems returns the problems of the conditions a route
+// is matched with once contour merges the conditions of its includes.
+func combinedConditionProblems(conds []contourv1.MatchCondition) []string {
+	var messages []string
+	for _, p := range append(headerConditionProblems(conds), queryConditionProblems(conds)...) {
+		messages = append(messages, p.message)
+	}
+
+	merged := ""
+	regex := false
+	for _, cond := range conds {
+		merged += cond.Prefix + cond.Exact + cond.Regex
+		regex = regex || cond.Regex != ""
+	}
+	if regex {
+		merged = duplicateSlashes.ReplaceAllString(merged, "/")
+		if _, err := regexp.Compile(merged); err != nil {
+			messages = append(messages, fmt.Sprintf("merged path regex %q is not a valid regex: %s", merged, err))
+		}
+	}
+	return messages
+}
+
+// newConditionProblems returns the problems of the combined conditions that
+// neither part has on its own.
+func newConditionProblems(inherited, own []contourv1.MatchCondition) []string {
+	known := append(combinedConditionProblems(inherited), combinedConditionProblems(own)...)
+	var messages []string
+	combined := append(append([]contourv1.MatchCondition{}, inherited...), own...)
+	for _, message := range combinedConditionProblems(combined) {
+		if !slices.Contains(known, message) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// includesOf returns the includes of the parent referring to the child.
+func includesOf(parent, child contourv1.HTTPProxy) []contourv1.Include {
+	var includes []contourv1.Include
+	for _, include := range parent.Spec.Includes {
+		namespace := include.Namespace
+		if namespace == "" {
+			namespace = parent.Namespace
+		}
+		if include.Name == child.Name && namespace == child.Namespace {
+			includes = append(includes, include)
+		}
+	}
+	return includes
+}
+
+// includeChains returns the conditions inherited by the proxy from every root
+// proxy including it. A root proxy inherits no conditions from itself.
+func includeChains(proxy contourv1.HTTPProxy, proxies []contourv1.HTTPProxy, visited map[string]bool) []includeChain {
+	if proxy.Spec.VirtualHost != nil {
+		return []includeChain{{root: proxyKey(proxy)}}
+	}
+
+	visited[proxyKey(proxy)] = true
+	defer delete(visited, proxyKey(proxy))
+
+	var chains []includeChain
+	for _, parent := range proxies {
+		if visited[proxyKey(parent)] {
+			continue
+		}
+		for _, include := range includesOf(parent, proxy) {
+			for _, chain := range includeChains(parent, proxies, visited) {
+				chains = append(chains, includeChain{
+					root:       chain.root,
+					conditions: append(append([]contourv1.MatchCondition{}, chain.conditions...), include.Conditions...),
+				})
+			}
+		}
+	}
+	return chains
+}
+
+func findProxy(proxies []contourv1.HTTPProxy, namespace, name string) (contourv1.HTTPProxy, bool) {
+	for _, p := range proxies {
+		if p.Namespace == namespace && p.Name == name {
+			return p, true
+		}
+	}
+	return contourv1.HTTPProxy{}, false
+}
+
+func (v Validator) checkMatchConditions(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+	checkBlock := func(path string, conds []contourv1.MatchCondition, include bool) {
+		problems := append(pathConditionProblems(conds, include), headerConditionProblems(conds)...)
+		problems = append(problems, queryConditionProblems(conds)...)
+		slices.SortStableFunc(problems, func(a, b conditionProblem) int {
+			return a.index - b.index
+		})
+		for _, p := range problems {
+			field := fmt.Sprintf("%s.conditions[%d]", path, p.index)
+			invalid(field, "%s: %s", field, p.message)
+		}
+	}
+
+	for i, include := range proxy.Spec.Includes {
+		checkBlock(fmt.Sprintf("includes[%d]", i), include.Conditions, true)
+	}
+	for i, route := range proxy.Spec.Routes {
+		checkBlock(fmt.Sprintf("routes[%d]", i), route.Conditions, false)
+	}
+
+	// Included proxies are matched with the conditions of every include
+	// leading to them, checked for the routes of the proxy and those of the
+	// proxies it includes.
+	if len(proxy.Spec.Includes) > 0 || (proxy.Spec.VirtualHost == nil && len(proxy.Spec.Routes) > 0) {
+		proxies, err := v.listHTTPProxies()
+		if err != nil {
+			return ValidationResponse{
+				Valid:  false,
+				Reason: "could not list resources",
+			}, err
+		}
+
+		chains := includeChains(proxy, proxies, map[string]bool{})
+		for i, route := range proxy.Spec.Routes {
+			field := fmt.Sprintf("routes[%d].conditions", i)
+			for _, chain := range chains {
+				for _, message := range newConditionProblems(chain.conditions, route.Conditions) {
+					invalid(field, "%s: combined with the conditions including it from %s, %s", field, chain.root, message)
+				}
+			}
+		}
+
+		for i, include := range proxy.Spec.Includes {
+			namespace := include.Namespace
+			if namespace == "" {
+				namespace = proxy.Namespace
+			}
+			child, found := findProxy(proxies, namespace, include.Name)
+			if !found {
+				continue
+			}
+			field := fmt.Sprintf("includes[%d].conditions", i)
+			for _, chain := range chains {
+				inherited := append(append([]contourv1.MatchCondition{}, chain.conditions...), include.Conditions...)
+				for j, route := range child.Spec.Routes {
+					for _, message := range newConditionProblems(inherited, route.Conditions) {
+						invalid(field, "%s: combined with routes[%d] of %s, %s", field, j, proxyKey(child), message)
+					}
+				}
+			}
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
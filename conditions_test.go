@@ -0,0 +1,170 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsValidProxyMatchConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    contourv1.Route
+		includes []contourv1.Include
+		expected ValidationResponse
+	}{
+		{
+			"valid conditions",
+			contourv1.Route{Conditions: []contourv1.MatchCondition{
+				{Regex: "/api/v[0-9]+"},
+				{Header: &contourv1.HeaderMatchCondition{Name: "X-Tenant", Exact: "a"}},
+				{Header: &contourv1.HeaderMatchCondition{Name: "X-Debug", NotPresent: true}},
+				{QueryParameter: &contourv1.QueryParameterMatchCondition{Name: "q", Prefix: "foo"}},
+			}},
+			[]contourv1.Include{{Name: "missing", Conditions: []contourv1.MatchCondition{{Prefix: "/missing"}}}},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"invalid paths",
+			contourv1.Route{Conditions: []contourv1.MatchCondition{
+				{Prefix: "api"},
+				{Prefix: "/v2"},
+				{Regex: "/(api"},
+			}},
+			[]contourv1.Include{{Name: "child", Conditions: []contourv1.MatchCondition{{Exact: "/child"}}}},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: includes[0].conditions[0]: exact conditions are not allowed in includes; routes[0].conditions[0]: prefix "api" must start with /; routes[0].conditions[1]: more than one prefix, exact or regex is not allowed in a condition block; routes[0].conditions[2]: more than one prefix, exact or regex is not allowed in a condition block`,
+				Violations: []Violation{
+					{Rule: ruleMatchConditions, Field: "spec.includes[0].conditions[0]", Message: "includes[0].conditions[0]: exact conditions are not allowed in includes"},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[0]", Message: `routes[0].conditions[0]: prefix "api" must start with /`},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[1]", Message: "routes[0].conditions[1]: more than one prefix, exact or regex is not allowed in a condition block"},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[2]", Message: "routes[0].conditions[2]: more than one prefix, exact or regex is not allowed in a condition block"},
+				},
+			},
+		},
+		{
+			"invalid header and query parameter matchers",
+			contourv1.Route{Conditions: []contourv1.MatchCondition{
+				{Regex: "/(api"},
+				{Header: &contourv1.HeaderMatchCondition{Name: "X-Tenant", Exact: "a"}},
+				{Header: &contourv1.HeaderMatchCondition{Name: "x-tenant", Exact: "b"}},
+				{Header: &contourv1.HeaderMatchCondition{Name: "X-Tenant", NotExact: "a"}},
+				{Header: &contourv1.HeaderMatchCondition{Name: "X-Debug", Present: true, Regex: "on"}},
+				{Header: &contourv1.HeaderMatchCondition{Name: "X-Id", Regex: "[0-9"}},
+				{QueryParameter: &contourv1.QueryParameterMatchCondition{Name: "q"}},
+				{QueryParameter: &contourv1.QueryParameterMatchCondition{Name: "page", Regex: "[0-9]{3,1}"}},
+			}},
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].conditions[0]: regex \"/(api\" is not a valid regex: error parsing regexp: missing closing ): `/(api`; routes[0].conditions[2]: header \"x-tenant\" has more than one exact condition; routes[0].conditions[3]: header \"X-Tenant\" has contradictory exact and notexact conditions; routes[0].conditions[4]: header \"X-Debug\" must set exactly one match; routes[0].conditions[5]: header \"X-Id\" regex \"[0-9\" is not a valid regex: error parsing regexp: missing closing ]: `[0-9`; routes[0].conditions[6]: query parameter \"q\" must set exactly one match; routes[0].conditions[7]: query parameter \"page\" regex \"[0-9]{3,1}\" is not a valid regex: error parsing regexp: invalid repeat count: `{3,1}`",
+				Violations: []Violation{
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[0]", Message: "routes[0].conditions[0]: regex \"/(api\" is not a valid regex: error parsing regexp: missing closing ): `/(api`"},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[2]", Message: `routes[0].conditions[2]: header "x-tenant" has more than one exact condition`},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[3]", Message: `routes[0].conditions[3]: header "X-Tenant" has contradictory exact and notexact conditions`},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[4]", Message: `routes[0].conditions[4]: header "X-Debug" must set exactly one match`},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[5]", Message: "routes[0].conditions[5]: header \"X-Id\" regex \"[0-9\" is not a valid regex: error parsing regexp: missing closing ]: `[0-9`"},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[6]", Message: `routes[0].conditions[6]: query parameter "q" must set exactly one match`},
+					{Rule: ruleMatchConditions, Field: "spec.routes[0].conditions[7]", Message: "routes[0].conditions[7]: query parameter \"page\" regex \"[0-9]{3,1}\" is not a valid regex: error parsing regexp: invalid repeat count: `{3,1}`"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			child := newTestProxy("default", "child", "")
+
+			validator := Validator{
+				Store:        &MemoryStore{Proxies: []contourv1.HTTPProxy{child}},
+				EnabledRules: []string{ruleMatchConditions},
+			}
+
+			proxy := newRouteProxy(tc.route)
+			proxy.Spec.Includes = tc.includes
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyMatchConditionsIncluded(t *testing.T) {
+	root := newTestProxy("default", "root", "foo.bar.com")
+	root.Spec.Includes = []contourv1.Include{{
+		Name: "middle",
+		Conditions: []contourv1.MatchCondition{
+			{Prefix: "/api(v1"},
+			{Header: &contourv1.HeaderMatchCondition{Name: "X-Tenant", Exact: "a"}},
+		},
+	}}
+	middle := newTestProxy("default", "middle", "")
+	middle.Spec.Includes = []contourv1.Include{{Name: "child"}}
+
+	child := newTestProxy("default", "child", "")
+	child.Spec.Routes = []contourv1.Route{
+		{Conditions: []contourv1.MatchCondition{{Prefix: "/users"}}},
+		{Conditions: []contourv1.MatchCondition{
+			{Regex: "/items/.*"},
+			{Header: &contourv1.HeaderMatchCondition{Name: "X-Tenant", NotExact: "a"}},
+		}},
+	}
+
+	validator := Validator{
+		Store:        &MemoryStore{Proxies: []contourv1.HTTPProxy{root, middle, child}},
+		EnabledRules: []string{ruleMatchConditions},
+	}
+
+	t.Run("included proxy", func(t *testing.T) {
+		resp, err := validator.IsValidProxy(child)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ValidationResponse{
+			Valid:  false,
+			Reason: "child is invalid: routes[1].conditions: combined with the conditions including it from default/root, header \"X-Tenant\" has contradictory exact and notexact conditions; routes[1].conditions: combined with the conditions including it from default/root, merged path regex \"/api(v1/items/.*\" is not a valid regex: error parsing regexp: missing closing ): `/api(v1/items/.*`",
+			Violations: []Violation{
+				{Rule: ruleMatchConditions, Field: "spec.routes[1].conditions", Message: `routes[1].conditions: combined with the conditions including it from default/root, header "X-Tenant" has contradictory exact and notexact conditions`},
+				{Rule: ruleMatchConditions, Field: "spec.routes[1].conditions", Message: "routes[1].conditions: combined with the conditions including it from default/root, merged path regex \"/api(v1/items/.*\" is not a valid regex: error parsing regexp: missing closing ): `/api(v1/items/.*`"},
+			},
+		}
+		if diff := cmp.Diff(resp, expected); diff != "" {
+			t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("including proxy", func(t *testing.T) {
+		resp, err := validator.IsValidProxy(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Valid {
+			t.Errorf("expected the root proxy to be valid as it does not include the routes directly, got %q", resp.Reason)
+		}
+
+		resp, err = validator.IsValidProxy(middle)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ValidationResponse{
+			Valid:  false,
+			Reason: "middle is invalid: includes[0].conditions: combined with routes[1] of default/child, header \"X-Tenant\" has contradictory exact and notexact conditions; includes[0].conditions: combined with routes[1] of default/child, merged path regex \"/api(v1/items/.*\" is not a valid regex: error parsing regexp: missing closing ): `/api(v1/items/.*`",
+			Violations: []Violation{
+				{Rule: ruleMatchConditions, Field: "spec.includes[0].conditions", Message: `includes[0].conditions: combined with routes[1] of default/child, header "X-Tenant" has contradictory exact and notexact conditions`},
+				{Rule: ruleMatchConditions, Field: "spec.includes[0].conditions", Message: "includes[0].conditions: combined with routes[1] of default/child, merged path regex \"/api(v1/items/.*\" is not a valid regex: error parsing regexp: missing closing ): `/api(v1/items/.*`"},
+			},
+		}
+		if diff := cmp.Diff(resp, expected); diff != "" {
+			t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+		}
+	})
+}
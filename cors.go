@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const ruleCORSPolicy = "cors-policy"
+
+// corsToken matches the methods and headers contour accepts in CORS policies.
+var corsToken = regexp.MustCompile("^[a-zA-Z0-9!#$%&'*+.^_`|~-]+$")
+
+// regexMeta holds the characters marking an origin as a regex. The dot is
+// left out as every host name contains one.
+const regexMeta = `\^$*+?()[]{}|`
+
+// wildcardProbes are origins no allow list means to include, an origin
+// matching any of them allows every origin.
+var wildcardProbes = []string{
+	"https://wildcard-probe.invalid",
+	"http://wildcard-probe.invalid:8080",
+}
+
+// wildcardOrigin reports whether the origin allows every origin, either as
+// the literal wildcard or as a regex matching arbitrary origins.
+func wildcardOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	// Like envoy, contour requires a regex to match the whole origin.
+	pattern, err := regexp.Compile("^(?:" + origin + ")$")
+	if err != nil {
+		return false
+	}
+	for _, probe := range wildcardProbes {
+		if pattern.MatchString(probe) {
+			return true
+		}
+	}
+	return false
+}
+
+// validOrigin follows contour: an origin is the wildcard, an exact
+// scheme://host[:port] origin or a regex, which must contain a regex
+// character other than the dot.
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	if !strings.ContainsAny(origin, regexMeta) {
+		parsed, err := url.Parse(origin)
+		return err == nil && parsed.Scheme != "" && parsed.Host != "" && origin == parsed.Scheme+"://"+parsed.Host
+	}
+	_, err := regexp.Compile(origin)
+	return err == nil
+}
+
+func (v Validator) checkCORSPolicy(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	if proxy.Spec.VirtualHost == nil || proxy.Spec.VirtualHost.CORSPolicy == nil {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+	policy := proxy.Spec.VirtualHost.CORSPolicy
+
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	const path = "virtualhost.corsPolicy"
+
+	if len(policy.AllowOrigin) == 0 {
+		field := path + ".allowOrigin"
+		invalid(field, "%s is required", field)
+	}
+	for i, origin := range policy.AllowOrigin {
+		field := fmt.Sprintf("%s.allowOrigin[%d]", path, i)
+		if !validOrigin(origin) {
+			invalid(field, "%s %q is neither an origin of the form scheme://host[:port] nor a valid regex", field, origin)
+			continue
+		}
+		if !wildcardOrigin(origin) {
+			continue
+		}
+		if policy.AllowCredentials {
+			invalid(field, "%s: a wildcard origin cannot be used with allowCredentials", field)
+		}
+		production, err := v.namespaceSelected(v.CORS.ProductionNamespaceSelector, proxy.Namespace)
+		if err != nil {
+			return ValidationResponse{
+				Valid:  false,
+				Reason: "could not list resources",
+			}, err
+		}
+		if production {
+			invalid(field, "%s: namespace %q is a production namespace and cannot allow every origin", field, proxy.Namespace)
+		}
+	}
+
+	if len(policy.AllowMethods) == 0 {
+		field := path + ".allowMethods"
+		invalid(field, "%s is required", field)
+	}
+	checkTokens := func(name string, values []contourv1.CORSHeaderValue) {
+		for i, value := range values {
+			if !corsToken.MatchString(string(value)) {
+				field := fmt.Sprintf("%s.%s[%d]", path, name, i)
+				invalid(field, "%s %q is not a valid token", field, value)
+			}
+		}
+	}
+	checkTokens("allowMethods", policy.AllowMethods)
+	checkTokens("allowHeaders", policy.AllowHeaders)
+	checkTokens("exposeHeaders", policy.ExposeHeaders)
+
+	if policy.MaxAge != "" {
+		field := path + ".maxAge"
+		maxAge, err := time.ParseDuration(policy.MaxAge)
+		max := v.CORS.MaxAge.Duration
+		switch {
+		case err != nil:
+			invalid(field, "%s: unable to parse duration %q", field, policy.MaxAge)
+		case maxAge < 0:
+			invalid(field, "%s %s must not be negative", field, policy.MaxAge)
+		case max > 0 && maxAge > max:
+			invalid(field, "%s %s exceeds the maximum of %s", field, policy.MaxAge, max)
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidOrigin(t *testing.T) {
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"*", true},
+		{"https://example.com", true},
+		{"http://example.com:8080", true},
+		{`https://.*\.example\.com`, true},
+		{"https://example.com/", false},
+		{"https://[example.com", false},
+		{"*.example.com", false},
+		{"foo", false},
+		{"http", false},
+		{"example.com", false},
+		{"^https://(a|b)\\.example\\.com$", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.origin, func(t *testing.T) {
+			if got := validOrigin(tc.origin); got != tc.expected {
+				t.Errorf("validOrigin(%q): got %t, want %t", tc.origin, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWildcardOrigin(t *testing.T) {
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"*", true},
+		{".*", true},
+		{"^.*$", true},
+		{".+", true},
+		{"https?://.*", true},
+		{"https://.*", true},
+		{"https://example.com", false},
+		{`https://.*\.example\.com`, false},
+		{"*.example.com", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.origin, func(t *testing.T) {
+			if got := wildcardOrigin(tc.origin); got != tc.expected {
+				t.Errorf("wildcardOrigin(%q): got %t, want %t", tc.origin, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyCORSPolicy(t *testing.T) {
+	restrictions := CORSRestrictions{
+		ProductionNamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"environment": "production"},
+		},
+		MaxAge: metav1.Duration{Duration: 24 * time.Hour},
+	}
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"environment": "production"}}},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		policy    *contourv1.CORSPolicy
+		expected  ValidationResponse
+	}{
+		{
+			"no cors policy",
+			"default",
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"valid cors policy",
+			"prod",
+			&contourv1.CORSPolicy{
+				AllowCredentials: true,
+				AllowOrigin:      []string{"https://example.com", `https://.*\.example\.com`},
+				AllowMethods:     []contourv1.CORSHeaderValue{"GET", "POST"},
+				AllowHeaders:     []contourv1.CORSHeaderValue{"Authorization"},
+				ExposeHeaders:    []contourv1.CORSHeaderValue{"X-Request-Id"},
+				MaxAge:           "10m",
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"wildcard outside of production",
+			"default",
+			&contourv1.CORSPolicy{
+				AllowOrigin:  []string{"*"},
+				AllowMethods: []contourv1.CORSHeaderValue{"GET"},
+				MaxAge:       "0s",
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"wildcard with credentials",
+			"default",
+			&contourv1.CORSPolicy{
+				AllowCredentials: true,
+				AllowOrigin:      []string{"https://example.com", "*"},
+				AllowMethods:     []contourv1.CORSHeaderValue{"GET"},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.corsPolicy.allowOrigin[1]: a wildcard origin cannot be used with allowCredentials",
+				Violations: []Violation{
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.allowOrigin[1]", Message: "virtualhost.corsPolicy.allowOrigin[1]: a wildcard origin cannot be used with allowCredentials"},
+				},
+			},
+		},
+		{
+			"wildcard in production",
+			"prod",
+			&contourv1.CORSPolicy{
+				AllowOrigin:  []string{".*"},
+				AllowMethods: []contourv1.CORSHeaderValue{"GET"},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: virtualhost.corsPolicy.allowOrigin[0]: namespace "prod" is a production namespace and cannot allow every origin`,
+				Violations: []Violation{
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.allowOrigin[0]", Message: `virtualhost.corsPolicy.allowOrigin[0]: namespace "prod" is a production namespace and cannot allow every origin`},
+				},
+			},
+		},
+		{
+			"invalid origins, tokens and max age",
+			"default",
+			&contourv1.CORSPolicy{
+				AllowOrigin:   []string{"https://[example.com"},
+				AllowHeaders:  []contourv1.CORSHeaderValue{"X Custom"},
+				ExposeHeaders: []contourv1.CORSHeaderValue{"X-Request-Id", "X:Trace"},
+				MaxAge:        "48h",
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: virtualhost.corsPolicy.allowOrigin[0] "https://[example.com" is neither an origin of the form scheme://host[:port] nor a valid regex; virtualhost.corsPolicy.allowMethods is required; virtualhost.corsPolicy.allowHeaders[0] "X Custom" is not a valid token; virtualhost.corsPolicy.exposeHeaders[1] "X:Trace" is not a valid token; virtualhost.corsPolicy.maxAge 48h exceeds the maximum of 24h0m0s`,
+				Violations: []Violation{
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.allowOrigin[0]", Message: `virtualhost.corsPolicy.allowOrigin[0] "https://[example.com" is neither an origin of the form scheme://host[:port] nor a valid regex`},
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.allowMethods", Message: "virtualhost.corsPolicy.allowMethods is required"},
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.allowHeaders[0]", Message: `virtualhost.corsPolicy.allowHeaders[0] "X Custom" is not a valid token`},
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.exposeHeaders[1]", Message: `virtualhost.corsPolicy.exposeHeaders[1] "X:Trace" is not a valid token`},
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.maxAge", Message: "virtualhost.corsPolicy.maxAge 48h exceeds the maximum of 24h0m0s"},
+				},
+			},
+		},
+		{
+			"missing origins and invalid max age",
+			"default",
+			&contourv1.CORSPolicy{
+				AllowMethods: []contourv1.CORSHeaderValue{"GET"},
+				MaxAge:       "-1s",
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.corsPolicy.allowOrigin is required; virtualhost.corsPolicy.maxAge -1s must not be negative",
+				Violations: []Violation{
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.allowOrigin", Message: "virtualhost.corsPolicy.allowOrigin is required"},
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.maxAge", Message: "virtualhost.corsPolicy.maxAge -1s must not be negative"},
+				},
+			},
+		},
+		{
+			"unparsable max age",
+			"default",
+			&contourv1.CORSPolicy{
+				AllowOrigin:  []string{"https://example.com"},
+				AllowMethods: []contourv1.CORSHeaderValue{"GET"},
+				MaxAge:       "1 day",
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: virtualhost.corsPolicy.maxAge: unable to parse duration "1 day"`,
+				Violations: []Violation{
+					{Rule: ruleCORSPolicy, Field: "spec.virtualhost.corsPolicy.maxAge", Message: `virtualhost.corsPolicy.maxAge: unable to parse duration "1 day"`},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{Namespaces: namespaces},
+				EnabledRules: []string{ruleCORSPolicy},
+				CORS:         restrictions,
+			}
+
+			proxy := newTestProxy(tc.namespace, "proxy-under-test", "foo.bar.com")
+			proxy.Spec.VirtualHost.CORSPolicy = tc.policy
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyCORSPolicyUnknownNamespace(t *testing.T) {
+	validator := Validator{
+		Store:        &MemoryStore{},
+		EnabledRules: []string{ruleCORSPolicy},
+		CORS: CORSRestrictions{
+			ProductionNamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"environment": "production"},
+			},
+		},
+	}
+
+	proxy := newTestProxy("prod", "proxy-under-test", "foo.bar.com")
+	proxy.Spec.VirtualHost.CORSPolicy = &contourv1.CORSPolicy{
+		AllowOrigin:  []string{"*"},
+		AllowMethods: []contourv1.CORSHeaderValue{"GET"},
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Valid {
+		t.Errorf("expected a valid proxy in a namespace that cannot be found, got %q", resp.Reason)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const ruleTLSCertificateDelegation = "tls-certificate-delegation"
+
+// IsValidTLSCertificateDelegation validates the delegations are well formed
+// since contour silently ignores delegations it cannot use.
+func (v Validator) IsValidTLSCertificateDelegation(delegation contourv1.TLSCertificateDelegation) (ValidationResponse, error) {
+	if !v.ruleEnabled(ruleTLSCertificateDelegation) {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	collector := violationCollector{rule: ruleTLSCertificateDelegation}
+	invalid := collector.invalid
+
+	if len(delegation.Spec.Delegations) == 0 {
+		invalid("delegations", "no delegations configured")
+	}
+
+	secrets := map[string]bool{}
+	for i, d := range delegation.Spec.Delegations {
+		if d.SecretName == "" {
+			field := fmt.Sprintf("delegations[%d].secretName", i)
+			invalid(field, "%s is required", field)
+		} else if secrets[d.SecretName] {
+			field := fmt.Sprintf("delegations[%d].secretName", i)
+			invalid(field, "%s %s is delegated more than once", field, d.SecretName)
+		}
+		secrets[d.SecretName] = true
+
+		if len(d.TargetNamespaces) == 0 {
+			field := fmt.Sprintf("delegations[%d].targetNamespaces", i)
+			invalid(field, "%s is required", field)
+		}
+		for j, ns := range d.TargetNamespaces {
+			if ns == "" {
+				field := fmt.Sprintf("delegations[%d].targetNamespaces[%d]", i, j)
+				invalid(field, "%s is empty", field)
+			}
+		}
+	}
+
+	return violationsResponse(delegation.Name, collector.violations), nil
+}
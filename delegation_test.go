@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsValidTLSCertificateDelegation(t *testing.T) {
+	validator := Validator{}
+
+	tests := []struct {
+		name        string
+		delegations []contourv1.CertificateDelegation
+		expected    ValidationResponse
+	}{
+		{
+			"valid delegations",
+			[]contourv1.CertificateDelegation{
+				{SecretName: "wildcard", TargetNamespaces: []string{"*"}},
+				{SecretName: "team-a", TargetNamespaces: []string{"team-a", "team-a-staging"}},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"no delegations",
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "delegation-under-test is invalid: no delegations configured",
+				Violations: []Violation{
+					{Rule: ruleTLSCertificateDelegation, Field: "spec.delegations", Message: "no delegations configured"},
+				},
+			},
+		},
+		{
+			"missing fields",
+			[]contourv1.CertificateDelegation{
+				{TargetNamespaces: []string{"team-a", ""}},
+				{SecretName: "team-b"},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "delegation-under-test is invalid: delegations[0].secretName is required; delegations[0].targetNamespaces[1] is empty; delegations[1].targetNamespaces is required",
+				Violations: []Violation{
+					{Rule: ruleTLSCertificateDelegation, Field: "spec.delegations[0].secretName", Message: "delegations[0].secretName is required"},
+					{Rule: ruleTLSCertificateDelegation, Field: "spec.delegations[0].targetNamespaces[1]", Message: "delegations[0].targetNamespaces[1] is empty"},
+					{Rule: ruleTLSCertificateDelegation, Field: "spec.delegations[1].targetNamespaces", Message: "delegations[1].targetNamespaces is required"},
+				},
+			},
+		},
+		{
+			"duplicate secret",
+			[]contourv1.CertificateDelegation{
+				{SecretName: "team-a", TargetNamespaces: []string{"team-a"}},
+				{SecretName: "team-a", TargetNamespaces: []string{"team-b"}},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "delegation-under-test is invalid: delegations[1].secretName team-a is delegated more than once",
+				Violations: []Violation{
+					{Rule: ruleTLSCertificateDelegation, Field: "spec.delegations[1].secretName", Message: "delegations[1].secretName team-a is delegated more than once"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delegation := contourv1.TLSCertificateDelegation{
+				Spec: contourv1.TLSCertificateDelegationSpec{
+					Delegations: tt.delegations,
+				},
+			}
+			delegation.SetName("delegation-under-test")
+
+			resp, err := validator.IsValidTLSCertificateDelegation(delegation)
+			if err != nil {
+				t.Fatalf("unexpected error validating delegation: %s", err.Error())
+			}
+
+			if diff := cmp.Diff(resp, tt.expected); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	eventComponent = "httpproxy-validation"
+
+	eventReasonConflictDenied = "ConflictingProxyDenied"
+)
+
+// NewEventRecorder creates a recorder writing events to the cluster. The
+// returned function stops the recorder.
+func NewEventRecorder(config *rest.Config) (record.EventRecorder, func(), error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: clientset.CoreV1().Events(""),
+	})
+
+	recorder := broadcaster.NewRecorder(runtimeScheme, corev1.EventSource{Component: eventComponent})
+	return recorder, broadcaster.Shutdown, nil
+}
+
+// DenialEvents records a warning event on the existing proxies a denied
+// HTTPProxy conflicted with, so their owners learn about the attempt. Events
+// for the same proxy, denied proxy and fqdn are recorded at most once per
+// Interval.
+type DenialEvents struct {
+	Recorder record.EventRecorder
+	Interval time.Duration
+
+	mu   sync.Mutex
+	now  func() time.Time
+	sent map[string]time.Time
+}
+
+func NewDenialEvents(recorder record.EventRecorder, interval time.Duration) *DenialEvents {
+	return &DenialEvents{
+		Recorder: recorder,
+		Interval: interval,
+		now:      time.Now,
+		sent:     map[string]time.Time{},
+	}
+}
+
+// Denied records events for a denied request. Dry run requests are ignored
+// since nothing was attempted.
+func (de *DenialEvents) Denied(req *admissionv1.AdmissionRequest, conflicts []contourv1.HTTPProxy) {
+	if req.DryRun != nil && *req.DryRun {
+		return
+	}
+
+	de.mu.Lock()
+	defer de.mu.Unlock()
+
+	now := de.now()
+	for key, sent := range de.sent {
+		if now.Sub(sent) >= de.Interval {
+			delete(de.sent, key)
+		}
+	}
+
+	for i := range conflicts {
+		proxy := &conflicts[i]
+		if proxy.Spec.VirtualHost == nil {
+			continue
+		}
+		fqdn := proxy.Spec.VirtualHost.Fqdn
+
+		key := fmt.Sprintf("%s/%s %s/%s %s", proxy.Namespace, proxy.Name, req.Namespace, req.Name, fqdn)
+		if _, ok := de.sent[key]; ok {
+			continue
+		}
+		de.sent[key] = now
+
+		de.Recorder.Eventf(proxy, corev1.EventTypeWarning, eventReasonConflictDenied,
+			"HTTPProxy %s/%s was denied claiming %s, which is served by this proxy", req.Namespace, req.Name, fqdn)
+	}
+}
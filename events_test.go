@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDenialEvents(t *testing.T) {
+	existing := []contourv1.HTTPProxy{
+		newTestProxy("team-a", "proxy", "foo.bar.com"),
+		newTestProxy("team-b", "proxy", "foo.bar.com"),
+	}
+	denied := func(namespace, name string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			Namespace: namespace,
+			Name:      name,
+			Operation: admissionv1.Create,
+		}
+	}
+	dryRun := denied("team-c", "proxy")
+	dryRun.DryRun = new(bool)
+	*dryRun.DryRun = true
+
+	type denial struct {
+		after time.Duration
+		req   *admissionv1.AdmissionRequest
+	}
+
+	tests := []struct {
+		name           string
+		denials        []denial
+		expectedEvents []string
+	}{
+		{
+			"every conflicting proxy is notified",
+			[]denial{
+				{0, denied("team-c", "proxy")},
+			},
+			[]string{
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+			},
+		},
+		{
+			"repeated denials are deduplicated",
+			[]denial{
+				{0, denied("team-c", "proxy")},
+				{time.Minute, denied("team-c", "proxy")},
+			},
+			[]string{
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+			},
+		},
+		{
+			"denials are recorded again after the interval",
+			[]denial{
+				{0, denied("team-c", "proxy")},
+				{10 * time.Minute, denied("team-c", "proxy")},
+			},
+			[]string{
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+			},
+		},
+		{
+			"different denied proxies are recorded",
+			[]denial{
+				{0, denied("team-c", "proxy")},
+				{time.Minute, denied("team-d", "proxy")},
+			},
+			[]string{
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-c/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-d/proxy was denied claiming foo.bar.com, which is served by this proxy",
+				"Warning ConflictingProxyDenied HTTPProxy team-d/proxy was denied claiming foo.bar.com, which is served by this proxy",
+			},
+		},
+		{
+			"dry run denials are ignored",
+			[]denial{
+				{0, dryRun},
+			},
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(100)
+			events := NewDenialEvents(recorder, 10*time.Minute)
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			events.now = func() time.Time { return now }
+
+			for _, d := range tc.denials {
+				now = now.Add(d.after)
+				events.Denied(d.req, existing)
+			}
+
+			if diff := cmp.Diff(recordedEvents(recorder), tc.expectedEvents); diff != "" {
+				t.Errorf("unexpected events (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+)
+
+const ruleExtensionService = "extension-service"
+
+// IsValidExtensionService validates the upstream services and protocol
+// settings contour requires before it will program the extension cluster.
+func (v Validator) IsValidExtensionService(extension contourv1alpha1.ExtensionService) (ValidationResponse, error) {
+	if !v.ruleEnabled(ruleExtensionService) {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	collector := violationCollector{rule: ruleExtensionService}
+	invalid := collector.invalid
+
+	if len(extension.Spec.Services) == 0 {
+		invalid("services", "no services configured")
+	}
+
+	for i, s := range extension.Spec.Services {
+		if s.Name == "" {
+			field := fmt.Sprintf("services[%d].name", i)
+			invalid(field, "%s is required", field)
+		}
+		if s.Port < 1 || s.Port > 65535 {
+			field := fmt.Sprintf("services[%d].port", i)
+			invalid(field, "%s %d is out of range", field, s.Port)
+		}
+	}
+
+	if p := extension.Spec.Protocol; p != nil && *p != "h2" && *p != "h2c" {
+		invalid("protocol", "protocol %q must be one of h2 or h2c", *p)
+	}
+
+	if pv := extension.Spec.ProtocolVersion; pv != "" && pv != contourv1alpha1.SupportProtocolVersion3 {
+		invalid("protocolVersion", "protocolVersion %q is not supported", pv)
+	}
+
+	return violationsResponse(extension.Name, collector.violations), nil
+}
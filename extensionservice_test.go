@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+)
+
+func TestIsValidExtensionService(t *testing.T) {
+	validator := Validator{}
+
+	h2 := "h2"
+	http1 := "http/1.1"
+
+	tests := []struct {
+		name     string
+		spec     contourv1alpha1.ExtensionServiceSpec
+		expected ValidationResponse
+	}{
+		{
+			"valid extension service",
+			contourv1alpha1.ExtensionServiceSpec{
+				Services:        []contourv1alpha1.ExtensionServiceTarget{{Name: "authz", Port: 9443}},
+				Protocol:        &h2,
+				ProtocolVersion: contourv1alpha1.SupportProtocolVersion3,
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"no services",
+			contourv1alpha1.ExtensionServiceSpec{},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "extension-under-test is invalid: no services configured",
+				Violations: []Violation{
+					{Rule: ruleExtensionService, Field: "spec.services", Message: "no services configured"},
+				},
+			},
+		},
+		{
+			"invalid services and protocol",
+			contourv1alpha1.ExtensionServiceSpec{
+				Services: []contourv1alpha1.ExtensionServiceTarget{
+					{Port: 9443},
+					{Name: "authz", Port: 70000},
+				},
+				Protocol:        &http1,
+				ProtocolVersion: contourv1alpha1.SupportProtocolVersion2,
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `extension-under-test is invalid: services[0].name is required; services[1].port 70000 is out of range; protocol "http/1.1" must be one of h2 or h2c; protocolVersion "v2" is not supported`,
+				Violations: []Violation{
+					{Rule: ruleExtensionService, Field: "spec.services[0].name", Message: "services[0].name is required"},
+					{Rule: ruleExtensionService, Field: "spec.services[1].port", Message: "services[1].port 70000 is out of range"},
+					{Rule: ruleExtensionService, Field: "spec.protocol", Message: `protocol "http/1.1" must be one of h2 or h2c`},
+					{Rule: ruleExtensionService, Field: "spec.protocolVersion", Message: `protocolVersion "v2" is not supported`},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extension := contourv1alpha1.ExtensionService{
+				Spec: tt.spec,
+			}
+			extension.SetName("extension-under-test")
+
+			resp, err := validator.IsValidExtensionService(extension)
+			if err != nil {
+				t.Fatalf("unexpected error validating extension service: %s", err.Error())
+			}
+
+			if diff := cmp.Diff(resp, tt.expected); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
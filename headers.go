@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const ruleHeaderPolicy = "header-policy"
+
+// defaultProtectedHeaders are the hop-by-hop headers and the forwarding
+// headers set by Envoy, used when the policy does not list its own.
+var defaultProtectedHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"X-Forwarded-For",
+	"X-Forwarded-Proto",
+}
+
+// dynamicHeaderValue matches the %REQ(header)% values contour uses to rewrite
+// the Host header from another request header.
+var dynamicHeaderValue = regexp.MustCompile(`%REQ\(([A-Za-z0-9-]+)\)%`)
+
+func (v Validator) protectedHeaders() []string {
+	if v.HeaderPolicy.ProtectedHeaders == nil {
+		return defaultProtectedHeaders
+	}
+	return v.HeaderPolicy.ProtectedHeaders
+}
+
+func (v Validator) headerProtected(namespace, name string) bool {
+	if slices.Contains(v.HeaderPolicy.AllowedNamespaces, namespace) {
+		return false
+	}
+	return slices.ContainsFunc(v.protectedHeaders(), func(protected string) bool {
+		return http.CanonicalHeaderKey(protected) == name
+	})
+}
+
+func (v Validator) checkHeaderPolicies(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	checkPolicy := func(path string, policy *contourv1.HeadersPolicy, request, service bool) {
+		if policy == nil {
+			return
+		}
+
+		set := map[string]bool{}
+		for k, header := range policy.Set {
+			field := fmt.Sprintf("%s.set[%d].name", path, k)
+			name := http.CanonicalHeaderKey(header.Name)
+			switch {
+			case len(validation.IsHTTPHeaderName(name)) > 0:
+				invalid(field, "%s %q is not a valid header name", field, header.Name)
+			case set[name]:
+				invalid(field, "%s %q is set more than once", field, header.Name)
+			case name == "Host" && !request:
+				invalid(field, "%s: the Host header can only be set in request headers", field)
+			case name == "Host" && service && dynamicHeaderValue.MatchString(header.Value):
+				invalid(field, "%s: the Host header of a service cannot be set from another header", field)
+			case v.headerProtected(proxy.Namespace, name):
+				invalid(field, "%s: header %q is protected", field, header.Name)
+			}
+			set[name] = true
+		}
+
+		removed := map[string]bool{}
+		for k, header := range policy.Remove {
+			field := fmt.Sprintf("%s.remove[%d]", path, k)
+			name := http.CanonicalHeaderKey(header)
+			switch {
+			case len(validation.IsHTTPHeaderName(name)) > 0:
+				invalid(field, "%s %q is not a valid header name", field, header)
+			case removed[name]:
+				invalid(field, "%s %q is removed more than once", field, header)
+			case set[name]:
+				invalid(field, "%s %q is also set", field, header)
+			case v.headerProtected(proxy.Namespace, name):
+				invalid(field, "%s: header %q is protected", field, header)
+			}
+			removed[name] = true
+		}
+	}
+
+	for i, route := range proxy.Spec.Routes {
+		checkPolicy(fmt.Sprintf("routes[%d].requestHeadersPolicy", i), route.RequestHeadersPolicy, true, false)
+		checkPolicy(fmt.Sprintf("routes[%d].responseHeadersPolicy", i), route.ResponseHeadersPolicy, false, false)
+		for j, service := range route.Services {
+			checkPolicy(fmt.Sprintf("routes[%d].services[%d].requestHeadersPolicy", i, j), service.RequestHeadersPolicy, true, true)
+			checkPolicy(fmt.Sprintf("routes[%d].services[%d].responseHeadersPolicy", i, j), service.ResponseHeadersPolicy, false, true)
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
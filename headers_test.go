@@ -0,0 +1,146 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsValidProxyHeaderPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		headerPolicy HeaderPolicy
+		route        contourv1.Route
+		expected     ValidationResponse
+	}{
+		{
+			"valid header policies",
+			HeaderPolicy{},
+			contourv1.Route{
+				RequestHeadersPolicy: &contourv1.HeadersPolicy{
+					Set:    []contourv1.HeaderValue{{Name: "Host", Value: "backend.internal"}, {Name: "X-Team", Value: "a"}},
+					Remove: []string{"X-Debug"},
+				},
+				ResponseHeadersPolicy: &contourv1.HeadersPolicy{
+					Set: []contourv1.HeaderValue{{Name: "Cache-Control", Value: "no-store"}},
+				},
+				Services: []contourv1.Service{{
+					Name: "backend",
+					Port: 80,
+					RequestHeadersPolicy: &contourv1.HeadersPolicy{
+						Set: []contourv1.HeaderValue{{Name: "Host", Value: "service.internal"}},
+					},
+				}},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"invalid names and duplicates",
+			HeaderPolicy{},
+			contourv1.Route{
+				RequestHeadersPolicy: &contourv1.HeadersPolicy{
+					Set:    []contourv1.HeaderValue{{Name: "x team", Value: "a"}, {Name: "X-Id", Value: "1"}, {Name: "x-id", Value: "2"}},
+					Remove: []string{"X-Debug", "x-debug", "X-ID", "bad:name"},
+				},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].requestHeadersPolicy.set[0].name "x team" is not a valid header name; routes[0].requestHeadersPolicy.set[2].name "x-id" is set more than once; routes[0].requestHeadersPolicy.remove[1] "x-debug" is removed more than once; routes[0].requestHeadersPolicy.remove[2] "X-ID" is also set; routes[0].requestHeadersPolicy.remove[3] "bad:name" is not a valid header name`,
+				Violations: []Violation{
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.set[0].name", Message: `routes[0].requestHeadersPolicy.set[0].name "x team" is not a valid header name`},
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.set[2].name", Message: `routes[0].requestHeadersPolicy.set[2].name "x-id" is set more than once`},
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.remove[1]", Message: `routes[0].requestHeadersPolicy.remove[1] "x-debug" is removed more than once`},
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.remove[2]", Message: `routes[0].requestHeadersPolicy.remove[2] "X-ID" is also set`},
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.remove[3]", Message: `routes[0].requestHeadersPolicy.remove[3] "bad:name" is not a valid header name`},
+				},
+			},
+		},
+		{
+			"host outside of request headers",
+			HeaderPolicy{},
+			contourv1.Route{
+				ResponseHeadersPolicy: &contourv1.HeadersPolicy{
+					Set: []contourv1.HeaderValue{{Name: "host", Value: "foo.bar.com"}},
+				},
+				Services: []contourv1.Service{{
+					Name: "backend",
+					Port: 80,
+					RequestHeadersPolicy: &contourv1.HeadersPolicy{
+						Set: []contourv1.HeaderValue{{Name: "Host", Value: "%REQ(X-Target)%"}},
+					},
+				}},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].responseHeadersPolicy.set[0].name: the Host header can only be set in request headers; routes[0].services[0].requestHeadersPolicy.set[0].name: the Host header of a service cannot be set from another header",
+				Violations: []Violation{
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].responseHeadersPolicy.set[0].name", Message: "routes[0].responseHeadersPolicy.set[0].name: the Host header can only be set in request headers"},
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].services[0].requestHeadersPolicy.set[0].name", Message: "routes[0].services[0].requestHeadersPolicy.set[0].name: the Host header of a service cannot be set from another header"},
+				},
+			},
+		},
+		{
+			"default protected headers",
+			HeaderPolicy{},
+			contourv1.Route{
+				RequestHeadersPolicy: &contourv1.HeadersPolicy{
+					Set:    []contourv1.HeaderValue{{Name: "x-forwarded-for", Value: "10.0.0.1"}},
+					Remove: []string{"Transfer-Encoding"},
+				},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].requestHeadersPolicy.set[0].name: header "x-forwarded-for" is protected; routes[0].requestHeadersPolicy.remove[0]: header "Transfer-Encoding" is protected`,
+				Violations: []Violation{
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.set[0].name", Message: `routes[0].requestHeadersPolicy.set[0].name: header "x-forwarded-for" is protected`},
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.remove[0]", Message: `routes[0].requestHeadersPolicy.remove[0]: header "Transfer-Encoding" is protected`},
+				},
+			},
+		},
+		{
+			"configured protected headers",
+			HeaderPolicy{ProtectedHeaders: []string{"x-user-id"}},
+			contourv1.Route{
+				RequestHeadersPolicy: &contourv1.HeadersPolicy{
+					Set: []contourv1.HeaderValue{{Name: "X-Forwarded-For", Value: "10.0.0.1"}, {Name: "X-User-Id", Value: "admin"}},
+				},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].requestHeadersPolicy.set[1].name: header "X-User-Id" is protected`,
+				Violations: []Violation{
+					{Rule: ruleHeaderPolicy, Field: "spec.routes[0].requestHeadersPolicy.set[1].name", Message: `routes[0].requestHeadersPolicy.set[1].name: header "X-User-Id" is protected`},
+				},
+			},
+		},
+		{
+			"allow-listed namespace",
+			HeaderPolicy{AllowedNamespaces: []string{"default"}},
+			contourv1.Route{
+				RequestHeadersPolicy: &contourv1.HeadersPolicy{
+					Set: []contourv1.HeaderValue{{Name: "X-Forwarded-For", Value: "10.0.0.1"}},
+				},
+			},
+			ValidationResponse{Valid: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleHeaderPolicy},
+				HeaderPolicy: tc.headerPolicy,
+			}
+
+			resp, err := validator.IsValidProxy(newRouteProxy(tc.route))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const ruleHealthCheckPolicy = "health-check-policy"
+
+// The values contour uses for health checks when a policy does not set them.
+const (
+	defaultHealthCheckInterval           = 10 * time.Second
+	defaultHealthCheckTimeout            = 2 * time.Second
+	defaultHealthCheckUnhealthyThreshold = 3
+	defaultHealthCheckHealthyThreshold   = 2
+)
+
+// healthCheck holds the fields shared by HTTP and TCP health check policies.
+type healthCheck struct {
+	intervalSeconds    int64
+	timeoutSeconds     int64
+	unhealthyThreshold int64
+	healthyThreshold   int64
+}
+
+func (v Validator) checkHealthCheckPolicies(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	// seconds returns the duration contour uses for a field in seconds.
+	seconds := func(field string, value int64, fallback time.Duration) time.Duration {
+		switch {
+		case value < 0:
+			invalid(field, "%s %d must not be negative", field, value)
+		case value > 0:
+			return time.Duration(value) * time.Second
+		}
+		return fallback
+	}
+	threshold := func(field string, value, fallback int64) {
+		if value == 0 {
+			value = fallback
+		}
+		switch maximum := v.Limits.MaxHealthCheckThreshold; {
+		case value < 0:
+			invalid(field, "%s %d must not be negative", field, value)
+		case maximum > 0 && value > maximum:
+			invalid(field, "%s %d exceeds the maximum of %d", field, value, maximum)
+		}
+	}
+	checkHealthCheck := func(path string, check healthCheck) {
+		interval := seconds(path+".intervalSeconds", check.intervalSeconds, defaultHealthCheckInterval)
+		timeout := seconds(path+".timeoutSeconds", check.timeoutSeconds, defaultHealthCheckTimeout)
+		if timeout >= interval {
+			invalid(path+".timeoutSeconds", "%s.timeoutSeconds: the timeout of %s must be shorter than the interval of %s", path, timeout, interval)
+		}
+		if minimum := v.Limits.MinHealthCheckInterval.Duration; interval < minimum {
+			invalid(path+".intervalSeconds", "%s.intervalSeconds: the interval of %s is below the minimum of %s", path, interval, minimum)
+		}
+		threshold(path+".unhealthyThresholdCount", check.unhealthyThreshold, defaultHealthCheckUnhealthyThreshold)
+		threshold(path+".healthyThresholdCount", check.healthyThreshold, defaultHealthCheckHealthyThreshold)
+	}
+
+	if policy := proxy.Spec.TCPProxy; policy != nil && policy.HealthCheckPolicy != nil {
+		hc := policy.HealthCheckPolicy
+		checkHealthCheck("tcpproxy.healthCheckPolicy", healthCheck{
+			intervalSeconds:    hc.IntervalSeconds,
+			timeoutSeconds:     hc.TimeoutSeconds,
+			unhealthyThreshold: int64(hc.UnhealthyThresholdCount),
+			healthyThreshold:   int64(hc.HealthyThresholdCount),
+		})
+	}
+
+	for i, route := range proxy.Spec.Routes {
+		hc := route.HealthCheckPolicy
+		if hc == nil {
+			continue
+		}
+		path := fmt.Sprintf("routes[%d].healthCheckPolicy", i)
+		if !strings.HasPrefix(hc.Path, "/") {
+			invalid(path+".path", "%s.path %q must start with /", path, hc.Path)
+		}
+		checkHealthCheck(path, healthCheck{
+			intervalSeconds:    hc.IntervalSeconds,
+			timeoutSeconds:     hc.TimeoutSeconds,
+			unhealthyThreshold: hc.UnhealthyThresholdCount,
+			healthyThreshold:   hc.HealthyThresholdCount,
+		})
+
+		// Follows envoy: status ranges are half open, from 100 up to 600.
+		for j, status := range hc.ExpectedStatuses {
+			field := fmt.Sprintf("%s.expectedStatuses[%d]", path, j)
+			switch {
+			case status.Start < 100 || status.Start > 599:
+				invalid(field, "%s: start %d must be between 100 and 599", field, status.Start)
+			case status.End < 101 || status.End > 600:
+				invalid(field, "%s: end %d must be between 101 and 600", field, status.End)
+			case status.Start >= status.End:
+				invalid(field, "%s: start %d must be below end %d", field, status.Start, status.End)
+			}
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsValidProxyHealthCheckPolicy(t *testing.T) {
+	limits := Limits{
+		MinHealthCheckInterval:  metav1.Duration{Duration: 5 * time.Second},
+		MaxHealthCheckThreshold: 5,
+	}
+
+	tests := []struct {
+		name     string
+		policy   *contourv1.HTTPHealthCheckPolicy
+		expected ValidationResponse
+	}{
+		{
+			"defaults",
+			&contourv1.HTTPHealthCheckPolicy{Path: "/healthz"},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"expected statuses",
+			&contourv1.HTTPHealthCheckPolicy{
+				Path:             "/healthz",
+				IntervalSeconds:  30,
+				TimeoutSeconds:   5,
+				ExpectedStatuses: []contourv1.HTTPStatusRange{{Start: 200, End: 300}, {Start: 429, End: 430}},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"invalid path and statuses",
+			&contourv1.HTTPHealthCheckPolicy{
+				Path:             "healthz",
+				ExpectedStatuses: []contourv1.HTTPStatusRange{{Start: 99, End: 200}, {Start: 200, End: 601}, {Start: 300, End: 200}},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].healthCheckPolicy.path "healthz" must start with /; routes[0].healthCheckPolicy.expectedStatuses[0]: start 99 must be between 100 and 599; routes[0].healthCheckPolicy.expectedStatuses[1]: end 601 must be between 101 and 600; routes[0].healthCheckPolicy.expectedStatuses[2]: start 300 must be below end 200`,
+				Violations: []Violation{
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.path", Message: `routes[0].healthCheckPolicy.path "healthz" must start with /`},
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.expectedStatuses[0]", Message: "routes[0].healthCheckPolicy.expectedStatuses[0]: start 99 must be between 100 and 599"},
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.expectedStatuses[1]", Message: "routes[0].healthCheckPolicy.expectedStatuses[1]: end 601 must be between 101 and 600"},
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.expectedStatuses[2]", Message: "routes[0].healthCheckPolicy.expectedStatuses[2]: start 300 must be below end 200"},
+				},
+			},
+		},
+		{
+			"timeout longer than interval",
+			&contourv1.HTTPHealthCheckPolicy{Path: "/healthz", IntervalSeconds: 5, TimeoutSeconds: 10},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].healthCheckPolicy.timeoutSeconds: the timeout of 10s must be shorter than the interval of 5s",
+				Violations: []Violation{
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.timeoutSeconds", Message: "routes[0].healthCheckPolicy.timeoutSeconds: the timeout of 10s must be shorter than the interval of 5s"},
+				},
+			},
+		},
+		{
+			"out of bounds",
+			&contourv1.HTTPHealthCheckPolicy{Path: "/healthz", IntervalSeconds: 3, TimeoutSeconds: -1, UnhealthyThresholdCount: 10, HealthyThresholdCount: -2},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].healthCheckPolicy.timeoutSeconds -1 must not be negative; routes[0].healthCheckPolicy.intervalSeconds: the interval of 3s is below the minimum of 5s; routes[0].healthCheckPolicy.unhealthyThresholdCount 10 exceeds the maximum of 5; routes[0].healthCheckPolicy.healthyThresholdCount -2 must not be negative",
+				Violations: []Violation{
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.timeoutSeconds", Message: "routes[0].healthCheckPolicy.timeoutSeconds -1 must not be negative"},
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.intervalSeconds", Message: "routes[0].healthCheckPolicy.intervalSeconds: the interval of 3s is below the minimum of 5s"},
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.unhealthyThresholdCount", Message: "routes[0].healthCheckPolicy.unhealthyThresholdCount 10 exceeds the maximum of 5"},
+					{Rule: ruleHealthCheckPolicy, Field: "spec.routes[0].healthCheckPolicy.healthyThresholdCount", Message: "routes[0].healthCheckPolicy.healthyThresholdCount -2 must not be negative"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleHealthCheckPolicy},
+				Limits:       limits,
+			}
+
+			resp, err := validator.IsValidProxy(newRouteProxy(contourv1.Route{HealthCheckPolicy: tc.policy}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyHealthCheckPolicyTCPProxy(t *testing.T) {
+	validator := Validator{
+		Store:        &MemoryStore{},
+		EnabledRules: []string{ruleHealthCheckPolicy},
+	}
+
+	proxy := newTestProxy("default", "proxy-under-test", "foo.bar.com")
+	proxy.Spec.TCPProxy = &contourv1.TCPProxy{
+		HealthCheckPolicy: &contourv1.TCPHealthCheckPolicy{TimeoutSeconds: 10},
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ValidationResponse{
+		Valid:  false,
+		Reason: "proxy-under-test is invalid: tcpproxy.healthCheckPolicy.timeoutSeconds: the timeout of 10s must be shorter than the interval of 10s",
+		Violations: []Violation{
+			{Rule: ruleHealthCheckPolicy, Field: "spec.tcpproxy.healthCheckPolicy.timeoutSeconds", Message: "tcpproxy.healthCheckPolicy.timeoutSeconds: the timeout of 10s must be shorter than the interval of 10s"},
+		},
+	}
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+	}
+}
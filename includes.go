@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+// forceDeleteAnnotation allows deleting a proxy that is still included when
+// set to "true".
+const forceDeleteAnnotation = "httpproxy-validation/force-delete"
+
+// IsDeletableProxy validates a proxy can be deleted without orphaning the
+// routes of the proxies including it. Deletes are always allowed unless
+// ProtectIncludedProxies is set.
+func (v Validator) IsDeletableProxy(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	if !v.ProtectIncludedProxies || proxy.GetAnnotations()[forceDeleteAnnotation] == "true" {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	proxies, err := v.listHTTPProxies()
+	if err != nil {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: "could not list resources",
+		}, err
+	}
+
+	var includers []string
+	for _, p := range proxies {
+		if v.proxyMatchesTargetIngressClasses(p) && includesProxy(p, proxy) {
+			includers = append(includers, p.Namespace+"/"+p.Name)
+		}
+	}
+
+	if len(includers) > 0 {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: fmt.Sprintf("%s is still included by %v, set the %s annotation to delete it anyway", proxy.Name, includers, forceDeleteAnnotation),
+		}, nil
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}, nil
+}
+
+// includesProxy reports whether the parent includes the child either through
+// a route include or a tcpproxy include. Includes without a namespace refer to
+// the parent's namespace.
+func includesProxy(parent, child contourv1.HTTPProxy) bool {
+	matches := func(name, namespace string) bool {
+		if namespace == "" {
+			namespace = parent.Namespace
+		}
+		return name == child.Name && namespace == child.Namespace
+	}
+
+	for _, include := range parent.Spec.Includes {
+		if matches(include.Name, include.Namespace) {
+			return true
+		}
+	}
+
+	if tcp := parent.Spec.TCPProxy; tcp != nil {
+		for _, include := range []*contourv1.TCPProxyInclude{tcp.Include, tcp.IncludesDeprecated} {
+			if include != nil && matches(include.Name, include.Namespace) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
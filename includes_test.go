@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsDeletableProxy(t *testing.T) {
+	root := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "foo.bar.com",
+			},
+			Includes: []contourv1.Include{
+				{Name: "child"},
+				{Name: "other-child", Namespace: "other"},
+			},
+		},
+	}
+	root.SetName("root")
+	root.SetNamespace("default")
+
+	tcpRoot := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "tcp.bar.com",
+			},
+			TCPProxy: &contourv1.TCPProxy{
+				Include: &contourv1.TCPProxyInclude{Name: "tcp-child"},
+			},
+		},
+	}
+	tcpRoot.SetName("tcp-root")
+	tcpRoot.SetNamespace("default")
+
+	untargetted := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: "not-targetted",
+			Includes: []contourv1.Include{
+				{Name: "untargetted-child"},
+			},
+		},
+	}
+	untargetted.SetName("untargetted")
+	untargetted.SetNamespace("default")
+
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return []contourv1.HTTPProxy{root, tcpRoot, untargetted}, nil
+		},
+	}
+
+	validator := Validator{
+		Store:                  store,
+		ProtectIncludedProxies: true,
+	}
+
+	tests := []struct {
+		name        string
+		namespace   string
+		proxyName   string
+		annotations map[string]string
+		expected    ValidationResponse
+	}{
+		{
+			"not included",
+			"default",
+			"unused",
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"included from same namespace",
+			"default",
+			"child",
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "child is still included by [default/root], set the httpproxy-validation/force-delete annotation to delete it anyway",
+			},
+		},
+		{
+			"included from other namespace",
+			"other",
+			"other-child",
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "other-child is still included by [default/root], set the httpproxy-validation/force-delete annotation to delete it anyway",
+			},
+		},
+		{
+			"same name in another namespace",
+			"other",
+			"child",
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"included by tcpproxy",
+			"default",
+			"tcp-child",
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "tcp-child is still included by [default/tcp-root], set the httpproxy-validation/force-delete annotation to delete it anyway",
+			},
+		},
+		{
+			"included by proxy not targetted",
+			"default",
+			"untargetted-child",
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"forced",
+			"default",
+			"child",
+			map[string]string{forceDeleteAnnotation: "true"},
+			ValidationResponse{Valid: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := contourv1.HTTPProxy{}
+			proxy.SetName(tt.proxyName)
+			proxy.SetNamespace(tt.namespace)
+			proxy.SetAnnotations(tt.annotations)
+
+			resp, err := validator.IsDeletableProxy(proxy)
+			if err != nil {
+				t.Fatalf("unexpected error validating proxy: %s", err.Error())
+			}
+
+			if diff := cmp.Diff(resp, tt.expected); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestIsDeletableProxyUnprotected(t *testing.T) {
+	validator := Validator{}
+
+	proxy := contourv1.HTTPProxy{}
+	proxy.SetName("child")
+
+	resp, err := validator.IsDeletableProxy(proxy)
+	if err != nil {
+		t.Fatalf("unexpected error validating proxy: %s", err.Error())
+	}
+
+	if diff := cmp.Diff(resp, ValidationResponse{Valid: true}); diff != "" {
+		t.Errorf("ValidationResponse: (-got +want)\n%s", diff)
+	}
+}
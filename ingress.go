@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// checkHostnameConflicts detects proxies claiming a hostname already served by
+// an Ingress or HTTPRoute handled by the same contour installation.
+func (v Validator) checkHostnameConflicts(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	if proxy.Spec.VirtualHost == nil {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	conflicts, err := v.hostnameConflicts([]string{proxy.Spec.VirtualHost.Fqdn})
+	if err != nil {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: "could not list resources",
+		}, err
+	}
+
+	if len(conflicts) > 0 {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: fmt.Sprintf("%s is in conflict with %v", proxy.Name, conflicts),
+		}, nil
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}, nil
+}
+
+// hostnameConflicts returns the Ingresses and HTTPRoutes serving any of the
+// hostnames.
+func (v Validator) hostnameConflicts(hostnames []string) ([]string, error) {
+	var conflicts []string
+
+	if v.CheckIngresses {
+		ingresses, err := v.listIngresses()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ing := range ingresses {
+			if v.ingressMatchesTargetIngressClasses(ing) && anyHostsOverlap(ingressHosts(ing), hostnames) {
+				conflicts = append(conflicts, "ingress/"+ing.Namespace+"/"+ing.Name)
+			}
+		}
+	}
+
+	if v.CheckHTTPRoutes {
+		routes, err := v.listHTTPRoutes()
+		if err != nil {
+			return nil, err
+		}
+
+		// HTTPRoutes are bound to contour through their parent Gateways rather
+		// than an ingress class so every route is considered.
+		for _, route := range routes {
+			var hosts []string
+			for _, host := range route.Spec.Hostnames {
+				hosts = append(hosts, string(host))
+			}
+			if anyHostsOverlap(hosts, hostnames) {
+				conflicts = append(conflicts, "httproute/"+route.Namespace+"/"+route.Name)
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// IsValidIngress validates an Ingress does not claim a hostname already served
+// by an HTTPProxy or HTTPRoute handled by the same contour installation.
+func (v Validator) IsValidIngress(ingress networkingv1.Ingress) (ValidationResponse, error) {
+	if !v.ingressMatchesTargetIngressClasses(ingress) || !v.ruleEnabled(ruleHostnameConflict) {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	proxies, err := v.listHTTPProxies()
+	if err != nil {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: "could not list resources",
+		}, err
+	}
+
+	hosts := ingressHosts(ingress)
+	var conflicts []string
+	for _, p := range proxies {
+		if p.Spec.VirtualHost != nil &&
+			v.proxyMatchesTargetIngressClasses(p) &&
+			anyHostsOverlap([]string{p.Spec.VirtualHost.Fqdn}, hosts) {
+			conflicts = append(conflicts, "httpproxy/"+proxyKey(p))
+		}
+	}
+
+	// Ingresses may share hosts with each other, so only HTTPRoutes are
+	// checked in addition to the proxies.
+	routeValidator := v
+	routeValidator.CheckIngresses = false
+	routeConflicts, err := routeValidator.hostnameConflicts(hosts)
+	if err != nil {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: "could not list resources",
+		}, err
+	}
+	conflicts = append(conflicts, routeConflicts...)
+
+	if len(conflicts) > 0 {
+		reason := fmt.Sprintf("%s is in conflict with %v", ingress.Name, conflicts)
+		return ValidationResponse{
+			Valid:  false,
+			Reason: reason,
+			Violations: []Violation{
+				{Rule: ruleHostnameConflict, Field: "spec.rules", Message: reason},
+			},
+		}, nil
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}, nil
+}
+
+func (v Validator) ingressMatchesTargetIngressClasses(ingress networkingv1.Ingress) bool {
+	var className string
+	if ingress.Spec.IngressClassName != nil {
+		className = *ingress.Spec.IngressClassName
+	}
+
+	return v.matchesTargetIngressClasses(ingress.GetAnnotations(), className)
+}
+
+func ingressHosts(ingress networkingv1.Ingress) []string {
+	var hosts []string
+	for _, r := range ingress.Spec.Rules {
+		if r.Host != "" && !slices.Contains(hosts, r.Host) {
+			hosts = append(hosts, r.Host)
+		}
+	}
+	return hosts
+}
+
+// hostsOverlap reports whether two hostnames, either of which may be a
+// wildcard, can match the same request.
+func hostsOverlap(a, b string) bool {
+	a, b = normalizeFqdn(a), normalizeFqdn(b)
+	return matchFqdn(a, b) || matchFqdn(b, a)
+}
+
+func anyHostsOverlap(hosts, others []string) bool {
+	for _, host := range hosts {
+		for _, other := range others {
+			if hostsOverlap(host, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
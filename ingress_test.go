@@ -0,0 +1,421 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+func newTestIngress(name, className string, hosts ...string) networkingv1.Ingress {
+	ingress := networkingv1.Ingress{}
+	ingress.SetName(name)
+	ingress.SetNamespace("default")
+	if className != "" {
+		ingress.Spec.IngressClassName = &className
+	}
+	for _, host := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: host})
+	}
+	return ingress
+}
+
+func newTestHTTPRoute(name string, hostnames ...string) gatewayv1beta1.HTTPRoute {
+	route := gatewayv1beta1.HTTPRoute{}
+	route.SetName(name)
+	route.SetNamespace("default")
+	for _, hostname := range hostnames {
+		route.Spec.Hostnames = append(route.Spec.Hostnames, gatewayv1beta1.Hostname(hostname))
+	}
+	return route
+}
+
+func TestIsValidProxyHostnameConflicts(t *testing.T) {
+	annotated := newTestIngress("ingress3", "", "annotated.bar.com")
+	annotated.SetAnnotations(map[string]string{
+		"kubernetes.io/ingress.class": "targetted",
+	})
+
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, nil
+		},
+		ingresses: func() ([]networkingv1.Ingress, error) {
+			return []networkingv1.Ingress{
+				newTestIngress("ingress1", "targetted", "foo.bar.com", "other.bar.com"),
+				newTestIngress("ingress2", "not-targetted", "baz.bar.com"),
+				annotated,
+				newTestIngress("ingress4", "targetted", "*.wild.bar.com"),
+			}, nil
+		},
+		routes: func() ([]gatewayv1beta1.HTTPRoute, error) {
+			return []gatewayv1beta1.HTTPRoute{
+				newTestHTTPRoute("route1", "route.bar.com", "FOO.bar.com"),
+				newTestHTTPRoute("route2", "*.gw.bar.com"),
+			}, nil
+		},
+	}
+
+	validator := Validator{
+		TargetIngressClasses: []string{"targetted"},
+		Store:                store,
+		CheckIngresses:       true,
+		CheckHTTPRoutes:      true,
+	}
+
+	tests := []struct {
+		name     string
+		fqdn     string
+		expected ValidationResponse
+	}{
+		{
+			"no conflict",
+			"new.bar.com",
+			ValidationResponse{Valid: true},
+		},
+		{
+			"ingress class not targetted",
+			"baz.bar.com",
+			ValidationResponse{Valid: true},
+		},
+		{
+			"conflicting ingress",
+			"other.bar.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [ingress/default/ingress1]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [ingress/default/ingress1]"},
+				},
+			},
+		},
+		{
+			"conflicting ingress, ingress class from annotation",
+			"annotated.bar.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [ingress/default/ingress3]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [ingress/default/ingress3]"},
+				},
+			},
+		},
+		{
+			"conflicting httproute",
+			"route.bar.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [httproute/default/route1]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [httproute/default/route1]"},
+				},
+			},
+		},
+		{
+			"conflicting ingress and httproute",
+			"foo.bar.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [ingress/default/ingress1 httproute/default/route1]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [ingress/default/ingress1 httproute/default/route1]"},
+				},
+			},
+		},
+		{
+			"conflicting wildcard ingress",
+			"app.wild.bar.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [ingress/default/ingress4]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [ingress/default/ingress4]"},
+				},
+			},
+		},
+		{
+			"conflicting wildcard httproute",
+			"app.gw.bar.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [httproute/default/route2]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [httproute/default/route2]"},
+				},
+			},
+		},
+		{
+			"wildcard fqdn",
+			"*.bar.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [ingress/default/ingress1 ingress/default/ingress3 httproute/default/route1]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [ingress/default/ingress1 ingress/default/ingress3 httproute/default/route1]"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := contourv1.HTTPProxy{
+				Spec: contourv1.HTTPProxySpec{
+					IngressClassName: "targetted",
+					VirtualHost: &contourv1.VirtualHost{
+						Fqdn: tt.fqdn,
+					},
+				},
+			}
+			proxy.SetName("proxy-under-test")
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatalf("unexpected error validating proxy: %s", err.Error())
+			}
+
+			if diff := cmp.Diff(resp, tt.expected); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyHostnameConflictsDisabled(t *testing.T) {
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, nil
+		},
+		ingresses: func() ([]networkingv1.Ingress, error) {
+			return nil, errors.New("ingresses should not be listed")
+		},
+		routes: func() ([]gatewayv1beta1.HTTPRoute, error) {
+			return nil, errors.New("routes should not be listed")
+		},
+	}
+
+	validator := Validator{
+		Store: store,
+	}
+
+	proxy := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "foo.bar.com",
+			},
+		},
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatalf("unexpected error validating proxy: %s", err.Error())
+	}
+
+	if diff := cmp.Diff(resp, ValidationResponse{Valid: true}); diff != "" {
+		t.Errorf("ValidationResponse: (-got +want)\n%s", diff)
+	}
+}
+
+func TestIsValidIngress(t *testing.T) {
+	p1 := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: "targetted",
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "foo.bar.com",
+			},
+		},
+	}
+	p1.SetName("proxy1")
+	p1.SetNamespace("default")
+
+	p2 := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: "not-targetted",
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "baz.bar.com",
+			},
+		},
+	}
+	p2.SetName("proxy2")
+	p2.SetNamespace("default")
+
+	child := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: "targetted",
+		},
+	}
+	child.SetName("child")
+	child.SetNamespace("default")
+
+	wildcard := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: "targetted",
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "*.wild.bar.com",
+			},
+		},
+	}
+	wildcard.SetName("wildcard")
+	wildcard.SetNamespace("default")
+
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return []contourv1.HTTPProxy{p1, p2, child, wildcard}, nil
+		},
+		ingresses: func() ([]networkingv1.Ingress, error) {
+			return []networkingv1.Ingress{
+				newTestIngress("ingress1", "targetted", "shared.bar.com"),
+			}, nil
+		},
+		routes: func() ([]gatewayv1beta1.HTTPRoute, error) {
+			return []gatewayv1beta1.HTTPRoute{
+				newTestHTTPRoute("route1", "route.bar.com"),
+				newTestHTTPRoute("route2", "*.gw.bar.com"),
+			}, nil
+		},
+	}
+
+	validator := Validator{
+		TargetIngressClasses: []string{"targetted"},
+		Store:                store,
+		CheckIngresses:       true,
+		CheckHTTPRoutes:      true,
+	}
+
+	tests := []struct {
+		name     string
+		ingress  networkingv1.Ingress
+		expected ValidationResponse
+	}{
+		{
+			"ingress class not targetted",
+			newTestIngress("ingress-under-test", "not-targetted", "foo.bar.com"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"no conflict",
+			newTestIngress("ingress-under-test", "targetted", "new.bar.com"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"hosts may be shared with other ingresses",
+			newTestIngress("ingress-under-test", "targetted", "shared.bar.com"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"proxy ingress class not targetted",
+			newTestIngress("ingress-under-test", "targetted", "baz.bar.com"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"conflicting proxy and route",
+			newTestIngress("ingress-under-test", "targetted", "new.bar.com", "foo.bar.com", "route.bar.com"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "ingress-under-test is in conflict with [httpproxy/default/proxy1 httproute/default/route1]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.rules", Message: "ingress-under-test is in conflict with [httpproxy/default/proxy1 httproute/default/route1]"},
+				},
+			},
+		},
+		{
+			"conflicting wildcard proxy and route",
+			newTestIngress("ingress-under-test", "targetted", "app.wild.bar.com", "app.gw.bar.com"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "ingress-under-test is in conflict with [httpproxy/default/wildcard httproute/default/route2]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.rules", Message: "ingress-under-test is in conflict with [httpproxy/default/wildcard httproute/default/route2]"},
+				},
+			},
+		},
+		{
+			"wildcard host",
+			newTestIngress("ingress-under-test", "targetted", "*.bar.com"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "ingress-under-test is in conflict with [httpproxy/default/proxy1 httproute/default/route1]",
+				Violations: []Violation{
+					{Rule: ruleHostnameConflict, Field: "spec.rules", Message: "ingress-under-test is in conflict with [httpproxy/default/proxy1 httproute/default/route1]"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := validator.IsValidIngress(tt.ingress)
+			if err != nil {
+				t.Fatalf("unexpected error validating ingress: %s", err.Error())
+			}
+
+			if diff := cmp.Diff(resp, tt.expected); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestIsValidIngressListsRoutesOnce(t *testing.T) {
+	listed := 0
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, nil
+		},
+		routes: func() ([]gatewayv1beta1.HTTPRoute, error) {
+			listed++
+			return []gatewayv1beta1.HTTPRoute{
+				newTestHTTPRoute("route1", "foo.bar.com"),
+			}, nil
+		},
+	}
+
+	validator := Validator{
+		Store:           store,
+		CheckHTTPRoutes: true,
+	}
+
+	resp, err := validator.IsValidIngress(newTestIngress("ingress-under-test", "", "foo.bar.com", "baz.bar.com", "new.bar.com"))
+	if err != nil {
+		t.Fatalf("unexpected error validating ingress: %s", err.Error())
+	}
+
+	if diff := cmp.Diff(listed, 1); diff != "" {
+		t.Errorf("HTTPRoute lists: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(resp.Reason, "ingress-under-test is in conflict with [httproute/default/route1]"); diff != "" {
+		t.Errorf("Reason: (-got +want)\n%s", diff)
+	}
+}
+
+func TestIsValidIngressStoreError(t *testing.T) {
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, errors.New("failed to query store")
+		},
+	}
+
+	validator := Validator{
+		Store: store,
+	}
+
+	resp, err := validator.IsValidIngress(newTestIngress("ingress-under-test", "", "foo.bar.com"))
+	if err == nil {
+		t.Fatalf("expected error validating ingress, got nil")
+	}
+
+	if diff := cmp.Diff(err.Error(), "failed to query store"); diff != "" {
+		t.Errorf("Unexpected error: (-got +want)\n%s", diff)
+	}
+
+	expectedResp := ValidationResponse{
+		Valid:  false,
+		Reason: "could not list resources",
+	}
+	if diff := cmp.Diff(resp, expectedResp); diff != "" {
+		t.Errorf("ValidationResponse: (-got +want)\n%s", diff)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const ruleJWTPolicy = "jwt-policy"
+
+// jwksService returns the Service a JWKS URI points at when its host is a
+// cluster DNS name of the form name.namespace.svc[.cluster-domain].
+func jwksService(jwksURL *url.URL) (namespace, name string, ok bool) {
+	labels := strings.Split(jwksURL.Hostname(), ".")
+	if len(labels) < 3 || labels[2] != "svc" {
+		return "", "", false
+	}
+	return labels[1], labels[0], true
+}
+
+// includingRoots returns the root proxies that include the proxy, directly or
+// through other included proxies.
+func includingRoots(proxy contourv1.HTTPProxy, proxies []contourv1.HTTPProxy) []contourv1.HTTPProxy {
+	var roots []contourv1.HTTPProxy
+	visited := map[string]bool{proxyKey(proxy): true}
+	queue := []contourv1.HTTPProxy{proxy}
+	for len(queue) > 0 {
+		child := queue[0]
+		queue = queue[1:]
+		for _, p := range proxies {
+			if visited[proxyKey(p)] || !includesProxy(p, child) {
+				continue
+			}
+			visited[proxyKey(p)] = true
+			if p.Spec.VirtualHost != nil {
+				roots = append(roots, p)
+			} else {
+				queue = append(queue, p)
+			}
+		}
+	}
+	return roots
+}
+
+func jwtProviderNames(proxy contourv1.HTTPProxy) []string {
+	var names []string
+	if proxy.Spec.VirtualHost != nil {
+		for _, provider := range proxy.Spec.VirtualHost.JWTProviders {
+			names = append(names, provider.Name)
+		}
+	}
+	return names
+}
+
+func (v Validator) checkJWTPolicies(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+	lookupFailed := func(err error) (ValidationResponse, error) {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: "could not list resources",
+		}, err
+	}
+
+	if vhost := proxy.Spec.VirtualHost; vhost != nil && len(vhost.JWTProviders) > 0 {
+		if vhost.TLS == nil || vhost.TLS.SecretName == "" {
+			invalid("virtualhost.jwtProviders", "virtualhost.jwtProviders require a virtual host that terminates TLS")
+		}
+
+		names := map[string]bool{}
+		defaultProvider := ""
+		for i, provider := range vhost.JWTProviders {
+			path := fmt.Sprintf("virtualhost.jwtProviders[%d]", i)
+			if names[provider.Name] {
+				field := path + ".name"
+				invalid(field, "%s %q is defined more than once", field, provider.Name)
+			}
+			names[provider.Name] = true
+
+			if provider.Default {
+				if defaultProvider != "" {
+					field := path + ".default"
+					invalid(field, "%s: provider %q is already the default", field, defaultProvider)
+				} else {
+					defaultProvider = provider.Name
+				}
+			}
+
+			field := path + ".remoteJWKS.uri"
+			jwksURL, err := url.Parse(provider.RemoteJWKS.URI)
+			if err != nil || jwksURL.Host == "" {
+				invalid(field, "%s %q is not a valid URL", field, provider.RemoteJWKS.URI)
+				continue
+			}
+			if jwksURL.Scheme != "https" {
+				invalid(field, "%s %q must use https", field, provider.RemoteJWKS.URI)
+			}
+			if namespace, name, ok := jwksService(jwksURL); ok {
+				service, ok, err := v.getService(namespace, name)
+				if err != nil {
+					return lookupFailed(err)
+				}
+				if ok && service == nil {
+					invalid(field, "%s: service %s/%s does not exist", field, namespace, name)
+				}
+			}
+		}
+	}
+
+	// Routes of included proxies use the providers of the root proxies
+	// including them.
+	var roots []contourv1.HTTPProxy
+	required := slices.ContainsFunc(proxy.Spec.Routes, func(route contourv1.Route) bool {
+		return route.JWTVerificationPolicy != nil && route.JWTVerificationPolicy.Require != ""
+	})
+	if proxy.Spec.VirtualHost == nil && required {
+		proxies, err := v.listHTTPProxies()
+		if err != nil {
+			return lookupFailed(err)
+		}
+		roots = includingRoots(proxy, proxies)
+	}
+
+	for i, route := range proxy.Spec.Routes {
+		policy := route.JWTVerificationPolicy
+		if policy == nil {
+			continue
+		}
+		path := fmt.Sprintf("routes[%d].jwtVerificationPolicy", i)
+		if policy.Require != "" && policy.Disabled {
+			invalid(path, "%s cannot both require a provider and be disabled", path)
+			continue
+		}
+		if policy.Require == "" {
+			continue
+		}
+
+		field := path + ".require"
+		if proxy.Spec.VirtualHost != nil {
+			if !slices.Contains(jwtProviderNames(proxy), policy.Require) {
+				invalid(field, "%s: JWT provider %q is not defined", field, policy.Require)
+			}
+			continue
+		}
+		for _, root := range roots {
+			if !slices.Contains(jwtProviderNames(root), policy.Require) {
+				invalid(field, "%s: JWT provider %q is not defined by root proxy %s", field, policy.Require, proxyKey(root))
+			}
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newJWTProvider(name, uri string) contourv1.JWTProvider {
+	return contourv1.JWTProvider{
+		Name:       name,
+		RemoteJWKS: contourv1.RemoteJWKS{URI: uri},
+	}
+}
+
+func TestIsValidProxyJWTPolicy(t *testing.T) {
+	services := []corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "auth", Name: "jwks"}},
+	}
+
+	tests := []struct {
+		name      string
+		tls       *contourv1.TLS
+		providers []contourv1.JWTProvider
+		policy    *contourv1.JWTVerificationPolicy
+		expected  ValidationResponse
+	}{
+		{
+			"valid providers",
+			&contourv1.TLS{SecretName: "tls"},
+			[]contourv1.JWTProvider{
+				newJWTProvider("in-cluster", "https://jwks.auth.svc.cluster.local/jwks.json"),
+				newJWTProvider("external", "https://idp.example.com/.well-known/jwks.json"),
+			},
+			&contourv1.JWTVerificationPolicy{Require: "external"},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"providers without tls",
+			nil,
+			[]contourv1.JWTProvider{newJWTProvider("external", "https://idp.example.com/jwks.json")},
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.jwtProviders require a virtual host that terminates TLS",
+				Violations: []Violation{
+					{Rule: ruleJWTPolicy, Field: "spec.virtualhost.jwtProviders", Message: "virtualhost.jwtProviders require a virtual host that terminates TLS"},
+				},
+			},
+		},
+		{
+			"inconsistent providers",
+			&contourv1.TLS{SecretName: "tls"},
+			[]contourv1.JWTProvider{
+				{Name: "a", Default: true, RemoteJWKS: contourv1.RemoteJWKS{URI: "http://idp.example.com/jwks.json"}},
+				{Name: "b", Default: true, RemoteJWKS: contourv1.RemoteJWKS{URI: "https://missing.auth.svc/jwks.json"}},
+				newJWTProvider("a", "/jwks.json"),
+			},
+			&contourv1.JWTVerificationPolicy{Require: "c"},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: virtualhost.jwtProviders[0].remoteJWKS.uri "http://idp.example.com/jwks.json" must use https; virtualhost.jwtProviders[1].default: provider "a" is already the default; virtualhost.jwtProviders[1].remoteJWKS.uri: service auth/missing does not exist; virtualhost.jwtProviders[2].name "a" is defined more than once; virtualhost.jwtProviders[2].remoteJWKS.uri "/jwks.json" is not a valid URL; routes[0].jwtVerificationPolicy.require: JWT provider "c" is not defined`,
+				Violations: []Violation{
+					{Rule: ruleJWTPolicy, Field: "spec.virtualhost.jwtProviders[0].remoteJWKS.uri", Message: `virtualhost.jwtProviders[0].remoteJWKS.uri "http://idp.example.com/jwks.json" must use https`},
+					{Rule: ruleJWTPolicy, Field: "spec.virtualhost.jwtProviders[1].default", Message: `virtualhost.jwtProviders[1].default: provider "a" is already the default`},
+					{Rule: ruleJWTPolicy, Field: "spec.virtualhost.jwtProviders[1].remoteJWKS.uri", Message: "virtualhost.jwtProviders[1].remoteJWKS.uri: service auth/missing does not exist"},
+					{Rule: ruleJWTPolicy, Field: "spec.virtualhost.jwtProviders[2].name", Message: `virtualhost.jwtProviders[2].name "a" is defined more than once`},
+					{Rule: ruleJWTPolicy, Field: "spec.virtualhost.jwtProviders[2].remoteJWKS.uri", Message: `virtualhost.jwtProviders[2].remoteJWKS.uri "/jwks.json" is not a valid URL`},
+					{Rule: ruleJWTPolicy, Field: "spec.routes[0].jwtVerificationPolicy.require", Message: `routes[0].jwtVerificationPolicy.require: JWT provider "c" is not defined`},
+				},
+			},
+		},
+		{
+			"require and disabled",
+			nil,
+			nil,
+			&contourv1.JWTVerificationPolicy{Require: "a", Disabled: true},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].jwtVerificationPolicy cannot both require a provider and be disabled",
+				Violations: []Violation{
+					{Rule: ruleJWTPolicy, Field: "spec.routes[0].jwtVerificationPolicy", Message: "routes[0].jwtVerificationPolicy cannot both require a provider and be disabled"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{Services: services},
+				EnabledRules: []string{ruleJWTPolicy},
+			}
+
+			proxy := newRouteProxy(contourv1.Route{JWTVerificationPolicy: tc.policy})
+			proxy.Spec.VirtualHost.TLS = tc.tls
+			proxy.Spec.VirtualHost.JWTProviders = tc.providers
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyJWTPolicyServiceLookupUnsupported(t *testing.T) {
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, nil
+		},
+	}
+
+	validator := Validator{
+		Store:        store,
+		EnabledRules: []string{ruleJWTPolicy},
+	}
+
+	proxy := newRouteProxy()
+	proxy.Spec.VirtualHost.TLS = &contourv1.TLS{SecretName: "tls"}
+	proxy.Spec.VirtualHost.JWTProviders = []contourv1.JWTProvider{
+		newJWTProvider("in-cluster", "https://missing.auth.svc/jwks.json"),
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(resp, ValidationResponse{Valid: true}); diff != "" {
+		t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+	}
+}
+
+func TestIsValidProxyJWTPolicyIncluded(t *testing.T) {
+	root := newTestProxy("default", "root", "foo.bar.com", "middle")
+	root.Spec.VirtualHost.TLS = &contourv1.TLS{SecretName: "tls"}
+	root.Spec.VirtualHost.JWTProviders = []contourv1.JWTProvider{newJWTProvider("a", "https://idp.example.com/jwks.json")}
+	other := newTestProxy("default", "other-root", "baz.bar.com", "child")
+	middle := newTestProxy("default", "middle", "", "child")
+
+	child := newTestProxy("default", "child", "")
+	child.Spec.Routes = []contourv1.Route{
+		{JWTVerificationPolicy: &contourv1.JWTVerificationPolicy{Require: "a"}},
+	}
+
+	validator := Validator{
+		Store:        &MemoryStore{Proxies: []contourv1.HTTPProxy{root, other, middle, child}},
+		EnabledRules: []string{ruleJWTPolicy},
+	}
+
+	resp, err := validator.IsValidProxy(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ValidationResponse{
+		Valid:  false,
+		Reason: `child is invalid: routes[0].jwtVerificationPolicy.require: JWT provider "a" is not defined by root proxy default/other-root`,
+		Violations: []Violation{
+			{Rule: ruleJWTPolicy, Field: "spec.routes[0].jwtVerificationPolicy.require", Message: `routes[0].jwtVerificationPolicy.require: JWT provider "a" is not defined by root proxy default/other-root`},
+		},
+	}
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+	}
+}
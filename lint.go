@@ -0,0 +1,275 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const (
+	exitValid   = 0
+	exitInvalid = 1
+	exitError   = 2
+)
+
+// LintResult is the validation outcome of a single manifest.
+type LintResult struct {
+	Manifest Manifest
+	Proxy    contourv1.HTTPProxy
+	Response ValidationResponse
+}
+
+// lintCommand validates the HTTPProxies in the given manifests and returns
+// the process exit code.
+func lintCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lint [flags] <file|directory|-> ...")
+		fs.PrintDefaults()
+	}
+
+	var ingressClasses, namespace, kubeconfig, kubeContext, output string
+	var cluster, checkIngresses, checkHTTPRoutes bool
+	fs.StringVar(&ingressClasses, "ingress-classes", "", "Comma separated list of ingress class names to validate against")
+	fs.StringVar(&namespace, "namespace", "default", "Namespace of manifests that do not set one")
+	fs.BoolVar(&cluster, "cluster", false, "Merge the manifests with the resources of a live cluster")
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig used with -cluster. Uses the default loading rules when empty")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context used with -cluster")
+	fs.BoolVar(&checkIngresses, "check-ingresses", false, "Detect hostname conflicts between HTTPProxies and Ingresses")
+	fs.BoolVar(&checkHTTPRoutes, "check-httproutes", false, "Detect hostname conflicts between HTTPProxies and Gateway API HTTPRoutes")
+	fs.StringVar(&output, "output", outputText, "Report format, one of text, json, sarif or junit")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	writeReport, ok := reportWriters[output]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown output format %q\n", output)
+		return exitError
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	manifests, err := ReadManifests(paths, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read manifests: %s\n", err.Error())
+		return exitError
+	}
+
+	overlay := manifestStore(manifests, namespace)
+	var store Store = overlay
+	if cluster {
+		clusterStore, err := NewKubeconfigStore(kubeconfig, kubeContext)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to setup cluster store: %s\n", err.Error())
+			return exitError
+		}
+
+		store, err = mergeStores(overlay, clusterStore, checkIngresses, checkHTTPRoutes)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to read cluster resources: %s\n", err.Error())
+			return exitError
+		}
+	}
+
+	validator := Validator{
+		Store:                store,
+		TargetIngressClasses: splitList(ingressClasses),
+		CheckIngresses:       checkIngresses,
+		CheckHTTPRoutes:      checkHTTPRoutes,
+	}
+
+	results, err := lintManifests(validator, manifests, namespace)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to validate manifests: %s\n", err.Error())
+		return exitError
+	}
+
+	if err := writeReport(stdout, results); err != nil {
+		fmt.Fprintf(stderr, "Failed to write report: %s\n", err.Error())
+		return exitError
+	}
+
+	return lintExitCode(results)
+}
+
+func lintManifests(validator Validator, manifests []Manifest, namespace string) ([]LintResult, error) {
+	var results []LintResult
+	for _, m := range manifests {
+		proxy, ok := m.Object.(*contourv1.HTTPProxy)
+		if !ok {
+			continue
+		}
+
+		p := *proxy
+		if p.Namespace == "" {
+			p.Namespace = namespace
+		}
+
+		resp, err := validator.IsValidProxy(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", m.Source, m.Line, err)
+		}
+
+		results = append(results, LintResult{
+			Manifest: m,
+			Proxy:    p,
+			Response: resp,
+		})
+	}
+
+	return results, nil
+}
+
+// manifestStore builds a store from the manifests, defaulting the namespace
+// of resources that do not set one.
+func manifestStore(manifests []Manifest, namespace string) *MemoryStore {
+	store := &MemoryStore{}
+	for _, m := range manifests {
+		switch obj := m.Object.(type) {
+		case *contourv1.HTTPProxy:
+			p := *obj
+			if p.Namespace == "" {
+				p.Namespace = namespace
+			}
+			store.Proxies = append(store.Proxies, p)
+		case *networkingv1.Ingress:
+			ing := *obj
+			if ing.Namespace == "" {
+				ing.Namespace = namespace
+			}
+			store.Ingresses = append(store.Ingresses, ing)
+		case *gatewayv1beta1.HTTPRoute:
+			route := *obj
+			if route.Namespace == "" {
+				route.Namespace = namespace
+			}
+			store.HTTPRoutes = append(store.HTTPRoutes, route)
+		case *corev1.Namespace:
+			store.Namespaces = append(store.Namespaces, *obj)
+		case *corev1.Service:
+			service := *obj
+			if service.Namespace == "" {
+				service.Namespace = namespace
+			}
+			store.Services = append(store.Services, service)
+		case *contourv1alpha1.ExtensionService:
+			extension := *obj
+			if extension.Namespace == "" {
+				extension.Namespace = namespace
+			}
+			store.ExtensionServices = append(store.ExtensionServices, extension)
+		}
+	}
+	return store
+}
+
+// mergedStore serves the resources merged from the manifests and a cluster.
+// Namespaces, Services and ExtensionServices missing from the manifests are
+// looked up in the cluster.
+type mergedStore struct {
+	*MemoryStore
+	base Store
+}
+
+func (s mergedStore) GetNamespace(name string) (*corev1.Namespace, error) {
+	if namespace, _ := s.MemoryStore.GetNamespace(name); namespace != nil {
+		return namespace, nil
+	}
+	if namespaceStore, ok := s.base.(NamespaceStore); ok {
+		return namespaceStore.GetNamespace(name)
+	}
+	return nil, nil
+}
+
+func (s mergedStore) GetService(namespace, name string) (*corev1.Service, error) {
+	if service, _ := s.MemoryStore.GetService(namespace, name); service != nil {
+		return service, nil
+	}
+	if serviceStore, ok := s.base.(ServiceStore); ok {
+		return serviceStore.GetService(namespace, name)
+	}
+	return nil, nil
+}
+
+func (s mergedStore) GetExtensionService(namespace, name string) (*contourv1alpha1.ExtensionService, error) {
+	if extension, _ := s.MemoryStore.GetExtensionService(namespace, name); extension != nil {
+		return extension, nil
+	}
+	if extensionStore, ok := s.base.(ExtensionServiceStore); ok {
+		return extensionStore.GetExtensionService(namespace, name)
+	}
+	return nil, nil
+}
+
+// mergeStores snapshots the base store and overlays the resources of the
+// overlay store, replacing resources with the same namespace and name.
+// Ingresses and HTTPRoutes are only read from the base store when requested.
+func mergeStores(overlay *MemoryStore, base *ClusterStore, ingresses, routes bool) (Store, error) {
+	merged := &MemoryStore{
+		Ingresses:         overlay.Ingresses,
+		HTTPRoutes:        overlay.HTTPRoutes,
+		Namespaces:        overlay.Namespaces,
+		Services:          overlay.Services,
+		ExtensionServices: overlay.ExtensionServices,
+	}
+
+	proxies, err := base.ListHTTPProxies()
+	if err != nil {
+		return nil, err
+	}
+	merged.Proxies = mergeObjects(proxies, overlay.Proxies)
+
+	if ingresses {
+		items, err := base.ListIngresses()
+		if err != nil {
+			return nil, err
+		}
+		merged.Ingresses = mergeObjects(items, overlay.Ingresses)
+	}
+
+	if routes {
+		items, err := base.ListHTTPRoutes()
+		if err != nil {
+			return nil, err
+		}
+		merged.HTTPRoutes = mergeObjects(items, overlay.HTTPRoutes)
+	}
+
+	return mergedStore{merged, base}, nil
+}
+
+func mergeObjects[T any, PT interface {
+	*T
+	metav1.Object
+}](base, overlay []T) []T {
+	key := func(obj T) string {
+		meta := PT(&obj)
+		return meta.GetNamespace() + "/" + meta.GetName()
+	}
+
+	overridden := map[string]bool{}
+	for _, obj := range overlay {
+		overridden[key(obj)] = true
+	}
+
+	var merged []T
+	for _, obj := range base {
+		if !overridden[key(obj)] {
+			merged = append(merged, obj)
+		}
+	}
+	return append(merged, overlay...)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	fake "k8s.io/client-go/rest/fake"
+)
+
+func TestLintCommand(t *testing.T) {
+	dir := t.TempDir()
+	manifests := `apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: proxy1
+spec:
+  virtualhost:
+    fqdn: foo.bar.com
+---
+apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: child
+spec:
+  routes:
+  - services:
+    - name: web
+      port: 80
+---
+apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: proxy2
+  namespace: other
+spec:
+  virtualhost:
+    fqdn: foo.bar.com
+`
+	path := filepath.Join(dir, "proxies.yaml")
+	if err := os.WriteFile(path, []byte(manifests), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		stdin        string
+		expectedCode int
+		expectedOut  string
+	}{
+		{
+			"conflicting manifests",
+			[]string{path},
+			"",
+			exitInvalid,
+			"FAIL " + path + ":1 default/proxy1: proxy1 is in conflict with [proxy2]\n" +
+				"FAIL " + path + ":19 other/proxy2: proxy2 is in conflict with [proxy1]\n" +
+				"3 HTTPProxies checked, 2 invalid\n",
+		},
+		{
+			"ingress class not targetted",
+			[]string{"-ingress-classes", "internal", path},
+			"",
+			exitValid,
+			"3 HTTPProxies checked, 0 invalid\n",
+		},
+		{
+			"valid stdin",
+			[]string{"-"},
+			"apiVersion: projectcontour.io/v1\nkind: HTTPProxy\nmetadata:\n  name: proxy1\nspec:\n  virtualhost:\n    fqdn: foo.bar.com\n",
+			exitValid,
+			"1 HTTPProxies checked, 0 invalid\n",
+		},
+		{
+			"no paths",
+			nil,
+			"",
+			exitError,
+			"",
+		},
+		{
+			"missing file",
+			[]string{filepath.Join(dir, "missing.yaml")},
+			"",
+			exitError,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := lintCommand(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Errorf("lintCommand %s: exit code got %d, want %d\nstderr: %s", tt.name, code, tt.expectedCode, stderr.String())
+			}
+
+			if diff := cmp.Diff(stdout.String(), tt.expectedOut); diff != "" {
+				t.Errorf("lintCommand %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestMergeObjects(t *testing.T) {
+	newProxy := func(namespace, name, fqdn string) contourv1.HTTPProxy {
+		p := contourv1.HTTPProxy{
+			Spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{
+					Fqdn: fqdn,
+				},
+			},
+		}
+		p.SetNamespace(namespace)
+		p.SetName(name)
+		return p
+	}
+
+	base := []contourv1.HTTPProxy{
+		newProxy("default", "proxy1", "old.bar.com"),
+		newProxy("default", "proxy2", "foo.bar.com"),
+	}
+	overlay := []contourv1.HTTPProxy{
+		newProxy("default", "proxy1", "new.bar.com"),
+		newProxy("other", "proxy2", "baz.bar.com"),
+	}
+
+	expected := []contourv1.HTTPProxy{
+		newProxy("default", "proxy2", "foo.bar.com"),
+		newProxy("default", "proxy1", "new.bar.com"),
+		newProxy("other", "proxy2", "baz.bar.com"),
+	}
+
+	if diff := cmp.Diff(mergeObjects(base, overlay), expected); diff != "" {
+		t.Errorf("mergeObjects: (-got +want)\n%s", diff)
+	}
+}
+
+func TestMergeStores(t *testing.T) {
+	responses := map[string]string{
+		"/apis/projectcontour.io/v1/httpproxies":                                   `{"metadata": {}, "items": []}`,
+		"/api/v1/namespaces/prod":                                                  `{"metadata": {"name": "prod", "labels": {"environment": "production"}}}`,
+		"/api/v1/namespaces/auth/services/jwks":                                    `{"metadata": {"name": "jwks", "namespace": "auth"}}`,
+		"/apis/projectcontour.io/v1alpha1/namespaces/auth/extensionservices/authz": `{"metadata": {"name": "authz", "namespace": "auth"}}`,
+	}
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		jsonOut, ok := responses[req.URL.Path]
+		if !ok {
+			return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("404 page not found"))}, nil
+		}
+
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(jsonOut))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	overlay := &MemoryStore{
+		Namespaces:        []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "staging"}}},
+		Services:          []corev1.Service{{ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "auth"}}},
+		ExtensionServices: []contourv1alpha1.ExtensionService{{ObjectMeta: metav1.ObjectMeta{Name: "ratelimit", Namespace: "auth"}}},
+	}
+	store, err := mergeStores(overlay, &ClusterStore{c.RESTClient()}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	namespaceStore := store.(NamespaceStore)
+	for _, name := range []string{"staging", "prod"} {
+		namespace, err := namespaceStore.GetNamespace(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if namespace == nil || namespace.Name != name {
+			t.Errorf("expected namespace %s, got %v", name, namespace)
+		}
+	}
+	if namespace, err := namespaceStore.GetNamespace("missing"); err != nil || namespace != nil {
+		t.Errorf("expected no namespace, got %v, %v", namespace, err)
+	}
+
+	serviceStore := store.(ServiceStore)
+	for _, name := range []string{"keys", "jwks"} {
+		service, err := serviceStore.GetService("auth", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if service == nil || service.Name != name {
+			t.Errorf("expected service auth/%s, got %v", name, service)
+		}
+	}
+	if service, err := serviceStore.GetService("auth", "missing"); err != nil || service != nil {
+		t.Errorf("expected no service, got %v, %v", service, err)
+	}
+
+	extensionStore := store.(ExtensionServiceStore)
+	for _, name := range []string{"ratelimit", "authz"} {
+		extension, err := extensionStore.GetExtensionService("auth", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if extension == nil || extension.Name != name {
+			t.Errorf("expected extension service auth/%s, got %v", name, extension)
+		}
+	}
+	if extension, err := extensionStore.GetExtensionService("auth", "missing"); err != nil || extension != nil {
+		t.Errorf("expected no extension service, got %v, %v", extension, err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const ruleLoadBalancerPolicy = "load-balancer-policy"
+
+const (
+	strategyRoundRobin           = "RoundRobin"
+	strategyWeightedLeastRequest = "WeightedLeastRequest"
+	strategyRandom               = "Random"
+	strategyCookie               = "Cookie"
+	strategyRequestHash          = "RequestHash"
+)
+
+// loadBalancerStrategies are the strategies contour accepts, any other falls
+// back to RoundRobin.
+var loadBalancerStrategies = []string{
+	strategyRoundRobin,
+	strategyWeightedLeastRequest,
+	strategyRandom,
+	strategyCookie,
+	strategyRequestHash,
+}
+
+func (v Validator) checkLoadBalancerPolicies(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	// checkPolicy returns the strategy contour uses for the policy.
+	checkPolicy := func(path string, policy *contourv1.LoadBalancerPolicy) string {
+		if policy == nil || policy.Strategy == "" {
+			return strategyRoundRobin
+		}
+
+		strategy := policy.Strategy
+		if !slices.Contains(loadBalancerStrategies, strategy) {
+			field := path + ".strategy"
+			invalid(field, "%s %q is not supported, contour falls back to %s", field, strategy, strategyRoundRobin)
+			strategy = strategyRoundRobin
+		}
+
+		hashPolicies := path + ".requestHashPolicies"
+		if strategy != strategyRequestHash {
+			if len(policy.RequestHashPolicies) > 0 {
+				invalid(hashPolicies, "%s are only used with the %s strategy", hashPolicies, strategyRequestHash)
+			}
+			return strategy
+		}
+		if len(policy.RequestHashPolicies) == 0 {
+			invalid(hashPolicies, "%s is required with the %s strategy", hashPolicies, strategyRequestHash)
+		}
+
+		sourceIP := false
+		headers := map[string]bool{}
+		parameters := map[string]bool{}
+		terminal := ""
+		for k, hash := range policy.RequestHashPolicies {
+			field := fmt.Sprintf("%s[%d]", hashPolicies, k)
+			if terminal != "" {
+				invalid(field, "%s is never used, %s always hashes the source IP and is terminal", field, terminal)
+			}
+
+			attributes := 0
+			for _, set := range []bool{hash.HashSourceIP, hash.HeaderHashOptions != nil, hash.QueryParameterHashOptions != nil} {
+				if set {
+					attributes++
+				}
+			}
+
+			switch {
+			case attributes != 1:
+				invalid(field, "%s must set exactly one of hashSourceIP, headerHashOptions or queryParameterHashOptions", field)
+			case hash.HashSourceIP:
+				if sourceIP {
+					invalid(field, "%s: hashSourceIP is set more than once", field)
+				}
+				sourceIP = true
+				if hash.Terminal && terminal == "" {
+					terminal = field
+				}
+			case hash.HeaderHashOptions != nil:
+				name := http.CanonicalHeaderKey(hash.HeaderHashOptions.HeaderName)
+				switch {
+				case len(validation.IsHTTPHeaderName(name)) > 0:
+					invalid(field+".headerHashOptions.headerName", "%s.headerHashOptions.headerName %q is not a valid header name", field, hash.HeaderHashOptions.HeaderName)
+				case headers[name]:
+					invalid(field+".headerHashOptions.headerName", "%s.headerHashOptions.headerName %q is hashed more than once", field, hash.HeaderHashOptions.HeaderName)
+				}
+				headers[name] = true
+			case hash.QueryParameterHashOptions != nil:
+				name := strings.ToLower(hash.QueryParameterHashOptions.ParameterName)
+				switch {
+				case name == "":
+					invalid(field+".queryParameterHashOptions.parameterName", "%s.queryParameterHashOptions.parameterName is required", field)
+				case parameters[name]:
+					invalid(field+".queryParameterHashOptions.parameterName", "%s.queryParameterHashOptions.parameterName %q is hashed more than once", field, hash.QueryParameterHashOptions.ParameterName)
+				}
+				parameters[name] = true
+			}
+		}
+		return strategy
+	}
+
+	checkSlowStart := func(path, strategy string, services []contourv1.Service) {
+		if strategy == strategyRoundRobin || strategy == strategyWeightedLeastRequest {
+			return
+		}
+		for j, service := range services {
+			if service.SlowStartPolicy != nil {
+				field := fmt.Sprintf("%s.services[%d].slowStartPolicy", path, j)
+				invalid(field, "%s is only supported with the %s or %s strategy", field, strategyRoundRobin, strategyWeightedLeastRequest)
+			}
+		}
+	}
+
+	for i, route := range proxy.Spec.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+		strategy := checkPolicy(path+".loadBalancerPolicy", route.LoadBalancerPolicy)
+		checkSlowStart(path, strategy, route.Services)
+	}
+	if tcp := proxy.Spec.TCPProxy; tcp != nil {
+		strategy := checkPolicy("tcpproxy.loadBalancerPolicy", tcp.LoadBalancerPolicy)
+		checkSlowStart("tcpproxy", strategy, tcp.Services)
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
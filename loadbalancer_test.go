@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsValidProxyLoadBalancerPolicy(t *testing.T) {
+	header := func(name string) contourv1.RequestHashPolicy {
+		return contourv1.RequestHashPolicy{HeaderHashOptions: &contourv1.HeaderHashOptions{HeaderName: name}}
+	}
+	query := func(name string) contourv1.RequestHashPolicy {
+		return contourv1.RequestHashPolicy{QueryParameterHashOptions: &contourv1.QueryParameterHashOptions{ParameterName: name}}
+	}
+
+	tests := []struct {
+		name     string
+		route    contourv1.Route
+		expected ValidationResponse
+	}{
+		{
+			"request hash",
+			contourv1.Route{LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []contourv1.RequestHashPolicy{
+					header("X-Tenant"),
+					query("session"),
+					{HashSourceIP: true, Terminal: true},
+				},
+			}},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"slow start with round robin",
+			contourv1.Route{
+				LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{Strategy: "WeightedLeastRequest"},
+				Services:           []contourv1.Service{{Name: "app", Port: 80, SlowStartPolicy: &contourv1.SlowStartPolicy{Window: "10s"}}},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"unsupported strategy",
+			contourv1.Route{LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{
+				Strategy:            "LeastRequest",
+				RequestHashPolicies: []contourv1.RequestHashPolicy{{HashSourceIP: true}},
+			}},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].loadBalancerPolicy.strategy "LeastRequest" is not supported, contour falls back to RoundRobin; routes[0].loadBalancerPolicy.requestHashPolicies are only used with the RequestHash strategy`,
+				Violations: []Violation{
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.strategy", Message: `routes[0].loadBalancerPolicy.strategy "LeastRequest" is not supported, contour falls back to RoundRobin`},
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies", Message: "routes[0].loadBalancerPolicy.requestHashPolicies are only used with the RequestHash strategy"},
+				},
+			},
+		},
+		{
+			"request hash without policies",
+			contourv1.Route{LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{Strategy: "RequestHash"}},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].loadBalancerPolicy.requestHashPolicies is required with the RequestHash strategy",
+				Violations: []Violation{
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies", Message: "routes[0].loadBalancerPolicy.requestHashPolicies is required with the RequestHash strategy"},
+				},
+			},
+		},
+		{
+			"invalid hash policies",
+			contourv1.Route{LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []contourv1.RequestHashPolicy{
+					{HashSourceIP: true, HeaderHashOptions: &contourv1.HeaderHashOptions{HeaderName: "X-Tenant"}},
+					header("X Tenant"),
+					header("x-user"),
+					header("X-User"),
+					query(""),
+					query("Session"),
+					query("session"),
+					{HashSourceIP: true, Terminal: true},
+					{HashSourceIP: true},
+				},
+			}},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].loadBalancerPolicy.requestHashPolicies[0] must set exactly one of hashSourceIP, headerHashOptions or queryParameterHashOptions; routes[0].loadBalancerPolicy.requestHashPolicies[1].headerHashOptions.headerName "X Tenant" is not a valid header name; routes[0].loadBalancerPolicy.requestHashPolicies[3].headerHashOptions.headerName "X-User" is hashed more than once; routes[0].loadBalancerPolicy.requestHashPolicies[4].queryParameterHashOptions.parameterName is required; routes[0].loadBalancerPolicy.requestHashPolicies[6].queryParameterHashOptions.parameterName "session" is hashed more than once; routes[0].loadBalancerPolicy.requestHashPolicies[8] is never used, routes[0].loadBalancerPolicy.requestHashPolicies[7] always hashes the source IP and is terminal; routes[0].loadBalancerPolicy.requestHashPolicies[8]: hashSourceIP is set more than once`,
+				Violations: []Violation{
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies[0]", Message: "routes[0].loadBalancerPolicy.requestHashPolicies[0] must set exactly one of hashSourceIP, headerHashOptions or queryParameterHashOptions"},
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies[1].headerHashOptions.headerName", Message: `routes[0].loadBalancerPolicy.requestHashPolicies[1].headerHashOptions.headerName "X Tenant" is not a valid header name`},
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies[3].headerHashOptions.headerName", Message: `routes[0].loadBalancerPolicy.requestHashPolicies[3].headerHashOptions.headerName "X-User" is hashed more than once`},
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies[4].queryParameterHashOptions.parameterName", Message: "routes[0].loadBalancerPolicy.requestHashPolicies[4].queryParameterHashOptions.parameterName is required"},
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies[6].queryParameterHashOptions.parameterName", Message: `routes[0].loadBalancerPolicy.requestHashPolicies[6].queryParameterHashOptions.parameterName "session" is hashed more than once`},
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies[8]", Message: "routes[0].loadBalancerPolicy.requestHashPolicies[8] is never used, routes[0].loadBalancerPolicy.requestHashPolicies[7] always hashes the source IP and is terminal"},
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].loadBalancerPolicy.requestHashPolicies[8]", Message: "routes[0].loadBalancerPolicy.requestHashPolicies[8]: hashSourceIP is set more than once"},
+				},
+			},
+		},
+		{
+			"slow start with cookie",
+			contourv1.Route{
+				LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{Strategy: "Cookie"},
+				Services:           []contourv1.Service{{Name: "app", Port: 80, SlowStartPolicy: &contourv1.SlowStartPolicy{Window: "10s"}}},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].services[0].slowStartPolicy is only supported with the RoundRobin or WeightedLeastRequest strategy",
+				Violations: []Violation{
+					{Rule: ruleLoadBalancerPolicy, Field: "spec.routes[0].services[0].slowStartPolicy", Message: "routes[0].services[0].slowStartPolicy is only supported with the RoundRobin or WeightedLeastRequest strategy"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleLoadBalancerPolicy},
+			}
+
+			resp, err := validator.IsValidProxy(newRouteProxy(tc.route))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyLoadBalancerPolicyTCPProxy(t *testing.T) {
+	validator := Validator{
+		Store:        &MemoryStore{},
+		EnabledRules: []string{ruleLoadBalancerPolicy},
+	}
+
+	proxy := newTestProxy("default", "proxy-under-test", "foo.bar.com")
+	proxy.Spec.TCPProxy = &contourv1.TCPProxy{
+		LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{Strategy: "Random"},
+		Services:           []contourv1.Service{{Name: "db", Port: 5432, SlowStartPolicy: &contourv1.SlowStartPolicy{Window: "10s"}}},
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ValidationResponse{
+		Valid:  false,
+		Reason: "proxy-under-test is invalid: tcpproxy.services[0].slowStartPolicy is only supported with the RoundRobin or WeightedLeastRequest strategy",
+		Violations: []Violation{
+			{Rule: ruleLoadBalancerPolicy, Field: "spec.tcpproxy.services[0].slowStartPolicy", Message: "tcpproxy.services[0].slowStartPolicy is only supported with the RoundRobin or WeightedLeastRequest strategy"},
+		},
+	}
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// newLogHandler creates the handler for the -log-format and -log-level flags.
+func newLogHandler(w io.Writer, format, level string) (slog.Handler, error) {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	switch format {
+	case logFormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case logFormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// discardHandler drops every record. It is used when the validator is not
+// given a logger, such as in the lint and audit commands.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+// decodeLogRecords decodes the JSON log records written to buf, dropping the
+// time which changes between runs.
+func decodeLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		delete(record, slog.TimeKey)
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNewLogHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		level         string
+		expectedDebug bool
+		expectedError bool
+	}{
+		{"text", logFormatText, "info", false, false},
+		{"json debug", logFormatJSON, "debug", true, false},
+		{"case insensitive level", logFormatJSON, "WARN", false, false},
+		{"unknown format", "yaml", "info", false, true},
+		{"unknown level", logFormatText, "verbose", false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler, err := newLogHandler(&buf, tc.format, tc.level)
+			if tc.expectedError {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if enabled := handler.Enabled(context.Background(), slog.LevelDebug); enabled != tc.expectedDebug {
+				t.Errorf("expected debug enabled %t, got %t", tc.expectedDebug, enabled)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyLogging(t *testing.T) {
+	existing := newTestProxy("team-a", "existing", "foo.bar.com")
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return []contourv1.HTTPProxy{existing}, nil
+		},
+	}
+
+	notTargetted := newTestProxy("team-b", "proxy", "foo.bar.com")
+	notTargetted.Spec.IngressClassName = "other"
+
+	tests := []struct {
+		name            string
+		enabledRules    []string
+		proxy           contourv1.HTTPProxy
+		expectedRecords []map[string]any
+	}{
+		{
+			"rule outcomes",
+			[]string{ruleFqdnConflict, ruleFqdnOwnership},
+			newTestProxy("team-b", "proxy", "foo.bar.com"),
+			[]map[string]any{
+				{
+					"level":             "INFO",
+					"msg":               "Proxy validated",
+					"fqdn":              "foo.bar.com",
+					"ingressClass":      "",
+					"ingressClassMatch": true,
+					"rules": map[string]any{
+						ruleFqdnConflict:       "fail",
+						ruleFqdnOwnership:      "pass",
+						ruleSharedFqdn:         "disabled",
+						ruleHostnameConflict:   "disabled",
+						ruleClusterConflict:    "disabled",
+						ruleServiceWeights:     "disabled",
+						ruleLoadBalancerPolicy: "disabled",
+						ruleHealthCheckPolicy:  "disabled",
+						ruleMatchConditions:    "disabled",
+						ruleTLSPolicy:          "disabled",
+						ruleAuthorization:      "disabled",
+						ruleJWTPolicy:          "disabled",
+						ruleCORSPolicy:         "disabled",
+						ruleRateLimitPolicy:    "disabled",
+						ruleHeaderPolicy:       "disabled",
+						ruleTimeoutPolicy:      "disabled",
+						ruleRetryPolicy:        "disabled",
+					},
+					"valid": false,
+				},
+			},
+		},
+		{
+			"ingress class not targetted",
+			nil,
+			notTargetted,
+			[]map[string]any{
+				{
+					"level":             "INFO",
+					"msg":               "Proxy validated",
+					"fqdn":              "foo.bar.com",
+					"ingressClass":      "other",
+					"ingressClassMatch": false,
+					"valid":             true,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			validator := Validator{
+				Store:        store,
+				EnabledRules: tc.enabledRules,
+				Logger:       slog.New(slog.NewJSONHandler(&buf, nil)),
+			}
+
+			if _, err := validator.IsValidProxy(tc.proxy); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(decodeLogRecords(t, &buf), tc.expectedRecords); diff != "" {
+				t.Errorf("unexpected log records (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,14 +1,36 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
 	"strings"
+	"syscall"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 )
 
+// kindPaths registers a path per kind so webhooks can be configured for a
+// single resource in addition to the shared /validate path.
+var kindPaths = map[string]metav1.GroupVersionKind{
+	"/validate/httpproxy":                httpProxyResource,
+	"/validate/ingress":                  ingressResource,
+	"/validate/tlscertificatedelegation": tlsCertificateDelegationResource,
+	"/validate/extensionservice":         extensionServiceResource,
+}
+
+// shutdownTimeout bounds how long the server waits for reviews in flight when
+// it is stopped.
+const shutdownTimeout = 10 * time.Second
+
 type serverConfig struct {
 	tlsKeyPath  string
 	tlsCertPath string
@@ -16,41 +38,270 @@ type serverConfig struct {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(lintCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "replay":
+			os.Exit(replayCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "audit":
+			os.Exit(auditCommand(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
+	os.Exit(serve())
+}
+
+// serve runs the webhook server until it fails or is asked to stop, and
+// returns the exit code. Everything set up along the way is torn down by
+// defers, so serve must return rather than exit.
+func serve() int {
 	var server serverConfig
 	var ingressClasses string
+	var policyName string
+	var checkIngresses, checkHTTPRoutes, protectIncludedProxies bool
+	var capturePath, captureRedact string
+	var captureSampleRate float64
+	var captureMaxSize int64
+	var captureMaxFiles int
+	var reconcileConflicts bool
+	var conflictMarker string
+	var reconcileResync time.Duration
+	var denialEvents bool
+	var denialEventInterval time.Duration
+	var logFormat, logLevel string
+	var remoteKubeconfig, remoteContexts, sharedFqdns string
 	flag.StringVar(&server.tlsKeyPath, "tls-key", "/etc/certs/tls.key", "Path to the TLS key")
 	flag.StringVar(&server.tlsCertPath, "tls-cert", "/etc/certs/tls.crt", "Path to the TLS certificate")
 	flag.IntVar(&server.port, "port", 8443, "Server port")
 	flag.StringVar(&ingressClasses, "ingress-classes", "", "Comma separated list of ingress class names to validate against")
+	flag.StringVar(&policyName, "policy-name", "", "Name of the HTTPProxyValidationPolicy to watch. Policies are not watched when empty")
+	flag.BoolVar(&checkIngresses, "check-ingresses", false, "Detect hostname conflicts between HTTPProxies and Ingresses")
+	flag.BoolVar(&checkHTTPRoutes, "check-httproutes", false, "Detect hostname conflicts between HTTPProxies and Gateway API HTTPRoutes")
+	flag.BoolVar(&protectIncludedProxies, "protect-included-proxies", false, "Deny deleting HTTPProxies that are still included by another HTTPProxy")
+	flag.StringVar(&capturePath, "capture", "", "Record admission reviews and responses as JSON lines to this file, or stdout with -. Disabled when empty")
+	flag.Float64Var(&captureSampleRate, "capture-sample-rate", 1, "Fraction of admission reviews recorded, between 0 and 1")
+	flag.StringVar(&captureRedact, "capture-redact", "", "Comma separated list of regular expressions matching the keys of object fields, such as annotations, to redact from recorded reviews")
+	flag.Int64Var(&captureMaxSize, "capture-max-size", 100*1024*1024, "Size in bytes at which the capture file is rotated")
+	flag.IntVar(&captureMaxFiles, "capture-max-files", 5, "Number of rotated capture files kept")
+	flag.BoolVar(&reconcileConflicts, "reconcile-conflicts", false, "Watch HTTPProxies and record events on proxies that are in conflict")
+	flag.StringVar(&conflictMarker, "conflict-marker", "", "Also mark conflicting HTTPProxies with the httpproxy-validation/conflict=true label or annotation, one of label or annotation")
+	flag.DurationVar(&reconcileResync, "reconcile-resync", 10*time.Minute, "Interval at which every HTTPProxy is reconciled again")
+	flag.BoolVar(&denialEvents, "denial-events", false, "Record a warning event on existing HTTPProxies when a conflicting HTTPProxy is denied")
+	flag.DurationVar(&denialEventInterval, "denial-event-interval", 10*time.Minute, "Minimum interval between repeated denial events for the same proxies and fqdn")
+	flag.StringVar(&logFormat, "log-format", logFormatText, "Log format, one of text or json")
+	flag.StringVar(&logLevel, "log-level", "info", "Minimum log level, one of debug, info, warn or error")
+	flag.StringVar(&remoteKubeconfig, "remote-kubeconfig", "", "Path to the kubeconfig holding the -remote-contexts. Uses the default loading rules when empty")
+	flag.StringVar(&remoteContexts, "remote-contexts", "", "Comma separated list of kubeconfig contexts of clusters whose HTTPProxies must not claim the same fqdn")
+	flag.StringVar(&sharedFqdns, "shared-fqdns", "", "Comma separated list of cluster:fqdn pairs allowing a remote cluster to serve the same fqdn, e.g. east:www.example.com. The fqdn may be a wildcard")
 	flag.Parse()
 
-	k8sStore, err := NewInClusterStore()
+	logHandler, err := newLogHandler(os.Stderr, logFormat, logLevel)
+	if err != nil {
+		slog.Error("Invalid logging flags", "error", err.Error())
+		return 1
+	}
+	slog.SetDefault(slog.New(logHandler))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if tracingEnabled() {
+		shutdownTracing, err := setupTracing(context.Background())
+		if err != nil {
+			slog.Error("Failed to setup tracing", "error", err.Error())
+			return 1
+		}
+		defer shutdownTracing(context.Background())
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		slog.Error("Failed to load in cluster config", "error", err.Error())
+		return 1
+	}
+
+	k8sStore, err := NewClusterStore(config)
 	if err != nil {
 		slog.Error("Failed to setup cluster store", "error", err.Error())
-		os.Exit(1)
+		return 1
 	}
 
 	httpProxyValidator := Validator{
-		Store:                k8sStore,
-		TargetIngressClasses: strings.Split(ingressClasses, ","),
+		Store:                  k8sStore,
+		TargetIngressClasses:   splitList(ingressClasses),
+		CheckIngresses:         checkIngresses,
+		CheckHTTPRoutes:        checkHTTPRoutes,
+		ProtectIncludedProxies: protectIncludedProxies,
 	}
 
-	if err := run(server, httpProxyValidator); err != nil {
-		slog.Error("Server exited.", "error", err.Error())
-		os.Exit(1)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	if remoteContexts != "" {
+		httpProxyValidator.SharedFqdns, err = parseSharedFqdns(sharedFqdns)
+		if err != nil {
+			slog.Error("Invalid shared fqdns", "error", err.Error())
+			return 1
+		}
+
+		multiClusterStore := &MultiClusterStore{Local: k8sStore}
+		for _, remoteContext := range splitList(remoteContexts) {
+			remoteConfig, err := kubeconfigConfig(remoteKubeconfig, remoteContext)
+			if err != nil {
+				slog.Error("Failed to load remote cluster config", "cluster", remoteContext, "error", err.Error())
+				return 1
+			}
+
+			remoteStore, err := NewInformerStore(remoteConfig, 0)
+			if err != nil {
+				slog.Error("Failed to setup remote cluster store", "cluster", remoteContext, "error", err.Error())
+				return 1
+			}
+			if !remoteStore.Run(stopCh) {
+				slog.Error("Failed to sync remote cluster HTTPProxies", "cluster", remoteContext)
+				return 1
+			}
+			multiClusterStore.Remotes = append(multiClusterStore.Remotes, RemoteCluster{Name: remoteContext, Store: remoteStore})
+		}
+		httpProxyValidator.Store = multiClusterStore
 	}
-}
 
-func run(serverConfig serverConfig, validator Validator) error {
 	admissionHandler := HTTPProxyAdmissionHandler{
-		Validator: validator,
+		Validator: httpProxyValidator,
+	}
+
+	if policyName != "" {
+		policyWatcher, err := NewPolicyWatcher(config, policyName)
+		if err != nil {
+			slog.Error("Failed to setup policy watcher", "error", err.Error())
+			return 1
+		}
+
+		if !policyWatcher.Run(stopCh) {
+			slog.Error("Failed to sync validation policy", "name", policyName)
+			return 1
+		}
+		admissionHandler.Policy = policyWatcher
+	}
+
+	var recorder record.EventRecorder
+	if reconcileConflicts || denialEvents {
+		var stopRecorder func()
+		recorder, stopRecorder, err = NewEventRecorder(config)
+		if err != nil {
+			slog.Error("Failed to setup event recorder", "error", err.Error())
+			return 1
+		}
+		defer stopRecorder()
+	}
+
+	if denialEvents {
+		admissionHandler.Events = NewDenialEvents(recorder, denialEventInterval)
+	}
+
+	if reconcileConflicts {
+		marker := ConflictMarker(conflictMarker)
+		if marker != ConflictMarkerNone && marker != ConflictMarkerLabel && marker != ConflictMarkerAnnotation {
+			slog.Error("Unknown conflict marker", "marker", conflictMarker)
+			return 1
+		}
+
+		reconciler, err := NewConflictReconciler(config, reconcileResync)
+		if err != nil {
+			slog.Error("Failed to setup conflict reconciler", "error", err.Error())
+			return 1
+		}
+
+		reconciler.Validator = httpProxyValidator
+		reconciler.Policy = admissionHandler.Policy
+		reconciler.Recorder = recorder
+		reconciler.Marker = marker
+		reconciler.Patcher = k8sStore
+		go reconciler.Run(stopCh)
+	}
+
+	var recorders []ReviewRecorder
+	if capturePath != "" {
+		capture, closeCapture, err := newServerCapture(capturePath, captureSampleRate, captureRedact, captureMaxSize, captureMaxFiles)
+		if err != nil {
+			slog.Error("Failed to setup admission review capture", "error", err.Error())
+			return 1
+		}
+		defer closeCapture()
+		recorders = append(recorders, capture)
 	}
 
+	if err := run(ctx, server, admissionHandler, recorders...); err != nil {
+		slog.Error("Server exited.", "error", err.Error())
+		return 1
+	}
+	return 0
+}
+
+// run serves admission reviews until ctx is done, then waits up to
+// shutdownTimeout for the reviews in flight.
+func run(ctx context.Context, serverConfig serverConfig, admissionHandler HTTPProxyAdmissionHandler, recorders ...ReviewRecorder) error {
 	mux := http.NewServeMux()
-	mux.Handle("/validate", AdmissionMiddleware(admissionHandler.Validate))
+	mux.Handle("/validate", AdmissionMiddleware(admissionHandler.ValidateContext, recorders...))
+	for path, kind := range kindPaths {
+		kindHandler := admissionHandler
+		kindHandler.Kinds = []metav1.GroupVersionKind{kind}
+		mux.Handle(path, AdmissionMiddleware(kindHandler.ValidateContext, recorders...))
+	}
 
 	addr := fmt.Sprintf(":%d", serverConfig.port)
 	slog.Info("Server starting", "addr", addr)
 
-	return http.ListenAndServeTLS(addr, serverConfig.tlsCertPath, serverConfig.tlsKeyPath, mux)
+	server := &http.Server{Addr: addr, Handler: mux}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServeTLS(serverConfig.tlsCertPath, serverConfig.tlsKeyPath)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// splitList splits a comma separated flag value, returning nil when empty.
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// newServerCapture sets up recording of admission reviews to stdout when path
+// is "-" or a rotating file otherwise.
+func newServerCapture(path string, sampleRate float64, redact string, maxSize int64, maxFiles int) (*Capture, func() error, error) {
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, nil, fmt.Errorf("sample rate %v must be between 0 and 1", sampleRate)
+	}
+
+	var patterns []*regexp.Regexp
+	for _, expr := range splitList(redact) {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid redact pattern %q: %w", expr, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	if path == "-" {
+		return NewCapture(os.Stdout, sampleRate, patterns), func() error { return nil }, nil
+	}
+
+	file, err := NewRotatingFile(path, maxSize, maxFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewCapture(file, sampleRate, patterns), file.Close, nil
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest is a single decoded document from a YAML or JSON source.
+type Manifest struct {
+	// Source is the file name the manifest was read from, or "-" for stdin.
+	Source string
+	// Line is the line of the source the document starts on, starting at 1.
+	Line int
+	// Raw is the document as it appeared in the source.
+	Raw    []byte
+	Object runtime.Object
+}
+
+// ReadManifests reads every document from the given files and directories.
+// Directories are walked for .yaml, .yml and .json files and "-" reads from
+// stdin. Documents of kinds not known to the validator are skipped.
+func ReadManifests(paths []string, stdin io.Reader) ([]Manifest, error) {
+	var manifests []Manifest
+	for _, path := range paths {
+		if path == "-" {
+			m, err := decodeManifests("-", stdin)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, m...)
+			continue
+		}
+
+		err := filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// Files given explicitly are read regardless of their extension.
+			if name != path && !isManifestFile(name) {
+				return nil
+			}
+
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			m, err := decodeManifests(name, f)
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, m...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifests, nil
+}
+
+func isManifestFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func decodeManifests(source string, r io.Reader) ([]Manifest, error) {
+	docs, err := splitDocuments(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	var manifests []Manifest
+	for _, doc := range docs {
+		data, err := yaml.YAMLToJSON(doc.raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, doc.line, err)
+		}
+		if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+			continue
+		}
+
+		obj, _, err := serializer.Decode(data, nil, nil)
+		if runtime.IsNotRegisteredError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, doc.line, err)
+		}
+
+		manifests = append(manifests, Manifest{
+			Source: source,
+			Line:   doc.line,
+			Raw:    doc.raw,
+			Object: obj,
+		})
+	}
+
+	return manifests, nil
+}
+
+type document struct {
+	line int
+	raw  []byte
+}
+
+// splitDocuments splits a multi-document YAML stream on "---" separators,
+// keeping track of the line each document starts on.
+func splitDocuments(r io.Reader) ([]document, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var docs []document
+	current := document{line: 1}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte("---")) && len(bytes.TrimSpace(line[3:])) == 0 {
+			docs = append(docs, current)
+			current = document{line: lineNumber + 1}
+			continue
+		}
+		current.raw = append(current.raw, line...)
+		current.raw = append(current.raw, '\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return append(docs, current), nil
+}
+
+// FieldPosition resolves the line and column of a field path, such as
+// spec.routes[0].services[1].port, within the source. The position of the
+// closest parent found is returned when the field is not set in the source.
+func (m Manifest) FieldPosition(field string) (line, column int) {
+	line, column = m.Line, 1
+
+	var root yaml3.Node
+	if err := yaml3.Unmarshal(m.Raw, &root); err != nil || len(root.Content) == 0 {
+		return line, column
+	}
+
+	node := root.Content[0]
+	for _, segment := range fieldSegments(field) {
+		next, key := childNode(node, segment)
+		if next == nil {
+			break
+		}
+		node = next
+		// Report keys rather than values so the position points at the field name.
+		if key != nil {
+			line, column = m.Line+key.Line-1, key.Column
+		} else {
+			line, column = m.Line+next.Line-1, next.Column
+		}
+	}
+
+	return line, column
+}
+
+// fieldSegments splits a field path into map keys and list indexes, e.g.
+// spec.routes[0] becomes spec, routes, [0]. Keys containing dots are written
+// in brackets, optionally quoted, e.g. metadata.annotations["kubernetes.io/ingress.class"]
+// becomes metadata, annotations, kubernetes.io/ingress.class.
+func fieldSegments(field string) []string {
+	var segments []string
+	for field != "" {
+		switch field[0] {
+		case '.':
+			field = field[1:]
+		case '[':
+			var key string
+			if len(field) > 1 && (field[1] == '"' || field[1] == '\'') {
+				key, field, _ = strings.Cut(field[2:], field[1:2])
+				_, field, _ = strings.Cut(field, "]")
+				segments = append(segments, key)
+				continue
+			}
+			key, field, _ = strings.Cut(field[1:], "]")
+			if _, err := strconv.Atoi(key); err == nil {
+				key = "[" + key + "]"
+			}
+			segments = append(segments, key)
+		default:
+			end := strings.IndexAny(field, ".[")
+			if end < 0 {
+				end = len(field)
+			}
+			segments = append(segments, field[:end])
+			field = field[end:]
+		}
+	}
+	return segments
+}
+
+func childNode(node *yaml3.Node, segment string) (value, key *yaml3.Node) {
+	if index, ok := strings.CutPrefix(segment, "["); ok {
+		i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+		if err != nil || node.Kind != yaml3.SequenceNode || i < 0 || i >= len(node.Content) {
+			return nil, nil
+		}
+		return node.Content[i], nil
+	}
+
+	if node.Kind != yaml3.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == segment {
+			return node.Content[i+1], node.Content[i]
+		}
+	}
+	return nil, nil
+}
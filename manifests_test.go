@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+const testManifests = `# leading comment
+apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: proxy1
+spec:
+  virtualhost:
+    fqdn: foo.bar.com
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+---
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: ingress1
+  namespace: web
+spec:
+  rules:
+  - host: baz.bar.com
+`
+
+func TestReadManifests(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "proxies.yaml"), []byte(testManifests), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested", "proxy.json"), []byte(`{"apiVersion": "projectcontour.io/v1", "kind": "HTTPProxy", "metadata": {"name": "proxy2"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdin := strings.NewReader("apiVersion: projectcontour.io/v1\nkind: HTTPProxy\nmetadata:\n  name: proxy3\n")
+
+	manifests, err := ReadManifests([]string{dir, "-"}, stdin)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	type position struct {
+		Source string
+		Line   int
+		Kind   string
+		Name   string
+	}
+
+	var got []position
+	for _, m := range manifests {
+		p := position{Source: m.Source, Line: m.Line}
+		switch obj := m.Object.(type) {
+		case *contourv1.HTTPProxy:
+			p.Kind, p.Name = "HTTPProxy", obj.Name
+		case *networkingv1.Ingress:
+			p.Kind, p.Name = "Ingress", obj.Name
+		}
+		got = append(got, p)
+	}
+
+	expected := []position{
+		{filepath.Join(dir, "nested", "proxy.json"), 1, "HTTPProxy", "proxy2"},
+		{filepath.Join(dir, "proxies.yaml"), 1, "HTTPProxy", "proxy1"},
+		{filepath.Join(dir, "proxies.yaml"), 16, "Ingress", "ingress1"},
+		{"-", 1, "HTTPProxy", "proxy3"},
+	}
+
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("ReadManifests: (-got +want)\n%s", diff)
+	}
+}
+
+func TestReadManifestsError(t *testing.T) {
+	stdin := strings.NewReader("apiVersion: projectcontour.io/v1\nkind: HTTPProxy\n---\nmetadata:\n  name: missing-kind\n")
+
+	_, err := ReadManifests([]string{"-"}, stdin)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if !strings.HasPrefix(err.Error(), "-:4: ") {
+		t.Errorf("expected error to reference the document position, got: %s", err.Error())
+	}
+}
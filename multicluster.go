@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sserializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const ruleClusterConflict = "cluster-conflict"
+
+// RemoteCluster is a cluster sharing the DNS zone of the local cluster.
+type RemoteCluster struct {
+	Name  string
+	Store Store
+}
+
+// ClusterProxy is a proxy listed from the remote cluster named Cluster.
+type ClusterProxy struct {
+	Cluster string
+	Proxy   contourv1.HTTPProxy
+}
+
+// MultiClusterStore serves the resources of the local cluster and lists the
+// proxies of the remote clusters separately, so a proxy can never claim to
+// belong to another cluster. Ingresses and HTTPRoutes are only listed from
+// the local cluster.
+type MultiClusterStore struct {
+	Local   Store
+	Remotes []RemoteCluster
+}
+
+func (ms *MultiClusterStore) ListHTTPProxies() ([]contourv1.HTTPProxy, error) {
+	return ms.Local.ListHTTPProxies()
+}
+
+func (ms *MultiClusterStore) ListRemoteHTTPProxies() ([]ClusterProxy, error) {
+	var proxies []ClusterProxy
+	for _, remote := range ms.Remotes {
+		remoteProxies, err := remote.Store.ListHTTPProxies()
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", remote.Name, err)
+		}
+		for _, p := range remoteProxies {
+			proxies = append(proxies, ClusterProxy{Cluster: remote.Name, Proxy: p})
+		}
+	}
+
+	return proxies, nil
+}
+
+func (ms *MultiClusterStore) ListIngresses() ([]networkingv1.Ingress, error) {
+	if local, ok := ms.Local.(IngressStore); ok {
+		return local.ListIngresses()
+	}
+	return nil, nil
+}
+
+func (ms *MultiClusterStore) ListHTTPRoutes() ([]gatewayv1beta1.HTTPRoute, error) {
+	if local, ok := ms.Local.(HTTPRouteStore); ok {
+		return local.ListHTTPRoutes()
+	}
+	return nil, nil
+}
+
+func (ms *MultiClusterStore) GetNamespace(name string) (*corev1.Namespace, error) {
+	if local, ok := ms.Local.(NamespaceStore); ok {
+		return local.GetNamespace(name)
+	}
+	return nil, nil
+}
+
+func (ms *MultiClusterStore) GetService(namespace, name string) (*corev1.Service, error) {
+	if local, ok := ms.Local.(ServiceStore); ok {
+		return local.GetService(namespace, name)
+	}
+	return nil, nil
+}
+
+func (ms *MultiClusterStore) GetExtensionService(namespace, name string) (*contourv1alpha1.ExtensionService, error) {
+	if local, ok := ms.Local.(ExtensionServiceStore); ok {
+		return local.GetExtensionService(namespace, name)
+	}
+	return nil, nil
+}
+
+// WithContext binds the local store to ctx. Remote clusters are served from
+// their informer caches and do not issue requests.
+func (ms *MultiClusterStore) WithContext(ctx context.Context) Store {
+	local, ok := ms.Local.(ContextStore)
+	if !ok {
+		return ms
+	}
+	return &MultiClusterStore{
+		Local:   local.WithContext(ctx),
+		Remotes: ms.Remotes,
+	}
+}
+
+// InformerStore serves the HTTPProxies of a cluster from an informer cache.
+type InformerStore struct {
+	informer cache.SharedIndexInformer
+}
+
+func NewInformerStore(config *rest.Config, resync time.Duration) (*InformerStore, error) {
+	proxyConfig := rest.CopyConfig(config)
+	proxyConfig.GroupVersion = &contourv1.GroupVersion
+	proxyConfig.APIPath = "/apis"
+	proxyConfig.NegotiatedSerializer = k8sserializer.NewCodecFactory(runtimeScheme).WithoutConversion()
+
+	client, err := rest.RESTClientFor(proxyConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	listWatch := cache.NewListWatchFromClient(client, "httpproxies", metav1.NamespaceAll, fields.Everything())
+	return &InformerStore{
+		informer: cache.NewSharedIndexInformer(listWatch, &contourv1.HTTPProxy{}, resync, cache.Indexers{}),
+	}, nil
+}
+
+// Run starts the informer and blocks until the initial list has been synced.
+func (is *InformerStore) Run(stopCh <-chan struct{}) bool {
+	go is.informer.Run(stopCh)
+	return cache.WaitForCacheSync(stopCh, is.informer.HasSynced)
+}
+
+func (is *InformerStore) ListHTTPProxies() ([]contourv1.HTTPProxy, error) {
+	var proxies []contourv1.HTTPProxy
+	for _, obj := range is.informer.GetStore().List() {
+		if proxy, ok := obj.(*contourv1.HTTPProxy); ok {
+			proxies = append(proxies, *proxy)
+		}
+	}
+	return proxies, nil
+}
+
+// parseSharedFqdns parses the -shared-fqdns flag, a comma separated list of
+// cluster:fqdn pairs, into the fqdns allowed per cluster.
+func parseSharedFqdns(value string) (map[string][]string, error) {
+	shared := map[string][]string{}
+	for _, entry := range splitList(value) {
+		cluster, fqdn, found := strings.Cut(entry, ":")
+		if !found || cluster == "" || fqdn == "" {
+			return nil, fmt.Errorf("invalid shared fqdn %q, expected cluster:fqdn", entry)
+		}
+		shared[cluster] = append(shared[cluster], fqdn)
+	}
+	return shared, nil
+}
+
+func (v Validator) sharedWithCluster(cluster, fqdn string) bool {
+	return slices.ContainsFunc(v.SharedFqdns[cluster], func(pattern string) bool {
+		return matchFqdn(pattern, fqdn)
+	})
+}
+
+func (v Validator) checkClusterConflicts(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	if proxy.Spec.VirtualHost == nil {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	proxies, err := v.listRemoteHTTPProxies()
+	if err != nil {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: "could not list resources",
+		}, err
+	}
+
+	fqdn := proxy.Spec.VirtualHost.Fqdn
+	normalized := normalizeFqdn(fqdn)
+	var conflictingProxies []string
+	for _, remote := range proxies {
+		p := remote.Proxy
+		if p.Spec.VirtualHost != nil &&
+			v.proxyMatchesTargetIngressClasses(p) &&
+			normalizeFqdn(p.Spec.VirtualHost.Fqdn) == normalized &&
+			!v.sharedWithCluster(remote.Cluster, normalized) {
+			conflictingProxies = append(conflictingProxies, remote.Cluster+"/"+p.Namespace+"/"+p.Name)
+		}
+	}
+
+	if len(conflictingProxies) > 0 {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: fmt.Sprintf("%s is already served in another cluster by %v", fqdn, conflictingProxies),
+		}, nil
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}, nil
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestMultiClusterStore(t *testing.T) {
+	local := newTestProxy("team-a", "local", "foo.bar.com")
+	remote := newTestProxy("team-a", "remote", "foo.bar.com")
+
+	localProxies := []contourv1.HTTPProxy{local}
+	store := &MultiClusterStore{
+		Local: &MemoryStore{Proxies: localProxies},
+		Remotes: []RemoteCluster{
+			{Name: "east", Store: &MemoryStore{Proxies: []contourv1.HTTPProxy{remote}}},
+			{Name: "west", Store: &MemoryStore{}},
+		},
+	}
+
+	proxies, err := store.ListHTTPProxies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(proxies, []contourv1.HTTPProxy{local}); diff != "" {
+		t.Errorf("ListHTTPProxies: (-got +want)\n%s", diff)
+	}
+
+	remoteProxies, err := store.ListRemoteHTTPProxies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(remoteProxies, []ClusterProxy{{Cluster: "east", Proxy: remote}}); diff != "" {
+		t.Errorf("ListRemoteHTTPProxies: (-got +want)\n%s", diff)
+	}
+
+	if diff := cmp.Diff(localProxies, []contourv1.HTTPProxy{local}); diff != "" {
+		t.Errorf("the local store was modified: (-got +want)\n%s", diff)
+	}
+}
+
+func TestIsValidProxyClusterAnnotationIsNotTrusted(t *testing.T) {
+	// Proxies used to be told apart by an annotation, letting a local proxy
+	// hide from the local rules by setting it.
+	annotated := newTestProxy("team-a", "annotated", "foo.bar.com")
+	annotated.SetAnnotations(map[string]string{"httpproxy-validation/cluster": "east"})
+	other := newTestProxy("team-a", "other", "other.bar.com")
+
+	proxies := []contourv1.HTTPProxy{annotated, other}
+	validator := Validator{
+		Store: &MultiClusterStore{
+			Local:   &MemoryStore{Proxies: proxies},
+			Remotes: []RemoteCluster{{Name: "east", Store: &MemoryStore{}}},
+		},
+		EnabledRules: []string{ruleFqdnConflict, ruleClusterConflict},
+	}
+
+	resp, err := validator.IsValidProxy(newTestProxy("team-b", "new", "foo.bar.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Valid {
+		t.Error("expected the annotated local proxy to conflict")
+	}
+
+	if diff := cmp.Diff(proxies, []contourv1.HTTPProxy{annotated, other}); diff != "" {
+		t.Errorf("the local store was modified: (-got +want)\n%s", diff)
+	}
+}
+
+func TestIsValidProxyClusterConflicts(t *testing.T) {
+	local := newTestProxy("team-a", "local", "local.bar.com")
+	east := newTestProxy("team-a", "east", "foo.bar.com")
+	otherClass := newTestProxy("team-a", "other-class", "other.bar.com")
+	otherClass.Spec.IngressClassName = "not-targetted"
+
+	store := &MultiClusterStore{
+		Local: &MemoryStore{Proxies: []contourv1.HTTPProxy{local}},
+		Remotes: []RemoteCluster{
+			{Name: "east", Store: &MemoryStore{Proxies: []contourv1.HTTPProxy{east, otherClass}}},
+			{Name: "west", Store: &MemoryStore{Proxies: []contourv1.HTTPProxy{newTestProxy("team-b", "west", "foo.bar.com")}}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		sharedFqdns      map[string][]string
+		proxy            contourv1.HTTPProxy
+		expectedResponse ValidationResponse
+	}{
+		{
+			"served in other clusters",
+			nil,
+			newTestProxy("team-a", "new", "foo.bar.com"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "foo.bar.com is already served in another cluster by [east/team-a/east west/team-b/west]",
+				Violations: []Violation{
+					{Rule: ruleClusterConflict, Field: "spec.virtualhost.fqdn", Message: "foo.bar.com is already served in another cluster by [east/team-a/east west/team-b/west]"},
+				},
+			},
+		},
+		{
+			"fqdn differs in case and trailing dot",
+			map[string][]string{"east": {"foo.bar.com"}},
+			newTestProxy("team-a", "new", "Foo.Bar.com."),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "Foo.Bar.com. is already served in another cluster by [west/team-b/west]",
+				Violations: []Violation{
+					{Rule: ruleClusterConflict, Field: "spec.virtualhost.fqdn", Message: "Foo.Bar.com. is already served in another cluster by [west/team-b/west]"},
+				},
+			},
+		},
+		{
+			"shared with one cluster",
+			map[string][]string{"east": {"foo.bar.com"}},
+			newTestProxy("team-a", "new", "foo.bar.com"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "foo.bar.com is already served in another cluster by [west/team-b/west]",
+				Violations: []Violation{
+					{Rule: ruleClusterConflict, Field: "spec.virtualhost.fqdn", Message: "foo.bar.com is already served in another cluster by [west/team-b/west]"},
+				},
+			},
+		},
+		{
+			"shared with every cluster by wildcard",
+			map[string][]string{"east": {"*.bar.com"}, "west": {"foo.bar.com"}},
+			newTestProxy("team-a", "new", "foo.bar.com"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"remote proxy not targetted",
+			nil,
+			newTestProxy("team-a", "new", "other.bar.com"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"local proxies are not cross cluster conflicts",
+			nil,
+			newTestProxy("team-a", "new", "local.bar.com"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"not a root proxy",
+			nil,
+			newTestProxy("team-a", "new", ""),
+			ValidationResponse{Valid: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        store,
+				EnabledRules: []string{ruleClusterConflict},
+				SharedFqdns:  tc.sharedFqdns,
+			}
+
+			resp, err := validator.IsValidProxy(tc.proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expectedResponse); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyFqdnConflictIgnoresRemoteClusters(t *testing.T) {
+	validator := Validator{
+		Store: &MultiClusterStore{
+			Local: &MemoryStore{},
+			Remotes: []RemoteCluster{
+				{Name: "east", Store: &MemoryStore{Proxies: []contourv1.HTTPProxy{newTestProxy("team-a", "new", "foo.bar.com")}}},
+			},
+		},
+		EnabledRules: []string{ruleFqdnConflict},
+	}
+
+	resp, err := validator.IsValidProxy(newTestProxy("team-b", "new", "foo.bar.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Valid {
+		t.Errorf("expected a valid proxy, got %q", resp.Reason)
+	}
+}
+
+func TestParseSharedFqdns(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expected      map[string][]string
+		expectedError bool
+	}{
+		{"empty", "", map[string][]string{}, false},
+		{"pairs", "east:foo.bar.com,east:*.baz.com,west:foo.bar.com", map[string][]string{"east": {"foo.bar.com", "*.baz.com"}, "west": {"foo.bar.com"}}, false},
+		{"missing cluster", ":foo.bar.com", nil, true},
+		{"missing fqdn", "east", nil, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			shared, err := parseSharedFqdns(tc.value)
+			if tc.expectedError {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(shared, tc.expected); diff != "" {
+				t.Errorf("parseSharedFqdns: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInformerStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/apis/projectcontour.io/v1/httpproxies" {
+			t.Errorf("unexpected request path: %s", req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if req.URL.Query().Get("watch") == "true" {
+			// Hold the watch open until the client goes away.
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-req.Context().Done()
+			return
+		}
+
+		_, _ = w.Write([]byte(`{
+	"apiVersion": "projectcontour.io/v1",
+	"kind": "HTTPProxyList",
+	"metadata": {"resourceVersion": "1"},
+	"items": [
+		{
+			"apiVersion": "projectcontour.io/v1",
+			"kind": "HTTPProxy",
+			"metadata": {"name": "proxy", "namespace": "team-a", "resourceVersion": "1"},
+			"spec": {"virtualhost": {"fqdn": "foo.bar.com"}}
+		}
+	]
+}`))
+	}))
+	defer server.Close()
+
+	store, err := NewInformerStore(&rest.Config{Host: server.URL}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	synced := make(chan bool)
+	go func() { synced <- store.Run(stopCh) }()
+
+	select {
+	case ok := <-synced:
+		if !ok {
+			t.Fatal("informer store failed to sync")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for informer store to sync")
+	}
+
+	proxies, err := store.ListHTTPProxies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proxies) != 1 || proxies[0].Name != "proxy" || proxies[0].Spec.VirtualHost.Fqdn != "foo.bar.com" {
+		t.Errorf("unexpected proxies: %v", proxies)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sserializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+type PolicySource interface {
+	Current() *HTTPProxyValidationPolicy
+}
+
+// PolicyWatcher keeps track of a single named HTTPProxyValidationPolicy
+// using an informer so that changes are applied without restarting.
+type PolicyWatcher struct {
+	name     string
+	informer cache.SharedIndexInformer
+
+	mu     sync.RWMutex
+	policy *HTTPProxyValidationPolicy
+}
+
+func NewPolicyWatcher(config *rest.Config, name string) (*PolicyWatcher, error) {
+	policyConfig := rest.CopyConfig(config)
+	policyConfig.GroupVersion = &policyGroupVersion
+	policyConfig.APIPath = "/apis"
+	policyConfig.NegotiatedSerializer = k8sserializer.NewCodecFactory(runtimeScheme).WithoutConversion()
+
+	client, err := rest.RESTClientFor(policyConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	listWatch := cache.NewFilteredListWatchFromClient(client, policyResource, metav1.NamespaceAll, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	})
+
+	pw := &PolicyWatcher{
+		name:     name,
+		informer: cache.NewSharedIndexInformer(listWatch, &HTTPProxyValidationPolicy{}, 0, cache.Indexers{}),
+	}
+
+	_, err = pw.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pw.setPolicy(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			pw.setPolicy(obj)
+		},
+		DeleteFunc: func(_ interface{}) {
+			pw.setPolicy(nil)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// Run starts the informer and blocks until the initial policy has been synced.
+func (pw *PolicyWatcher) Run(stopCh <-chan struct{}) bool {
+	go pw.informer.Run(stopCh)
+	return cache.WaitForCacheSync(stopCh, pw.informer.HasSynced)
+}
+
+// Current returns the active policy, or nil when no policy exists.
+func (pw *PolicyWatcher) Current() *HTTPProxyValidationPolicy {
+	pw.mu.RLock()
+	defer pw.mu.RUnlock()
+	return pw.policy
+}
+
+func (pw *PolicyWatcher) setPolicy(obj interface{}) {
+	policy, ok := obj.(*HTTPProxyValidationPolicy)
+	if obj != nil && !ok {
+		slog.Error("Unexpected object in policy informer", "type", fmt.Sprintf("%T", obj))
+		return
+	}
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if policy == nil {
+		slog.Info("Validation policy removed, using defaults", "name", pw.name)
+		pw.policy = nil
+		return
+	}
+
+	slog.Info("Validation policy applied", "name", policy.Name, "generation", policy.Generation)
+	pw.policy = policy.DeepCopy()
+}
+
+// applyPolicy returns a copy of the validator with the policy settings applied
+// on top of the configured defaults.
+func applyPolicy(validator Validator, policy *HTTPProxyValidationPolicy) Validator {
+	if policy == nil {
+		return validator
+	}
+
+	if len(policy.Spec.TargetIngressClasses) > 0 {
+		validator.TargetIngressClasses = policy.Spec.TargetIngressClasses
+	}
+	if len(policy.Spec.EnabledRules) > 0 {
+		validator.EnabledRules = policy.Spec.EnabledRules
+	}
+	validator.FqdnOwnership = policy.Spec.FqdnOwnership
+	if policy.Spec.Limits != nil {
+		validator.Limits = *policy.Spec.Limits
+	}
+	if policy.Spec.HeaderPolicy != nil {
+		validator.HeaderPolicy = *policy.Spec.HeaderPolicy
+	}
+	if policy.Spec.RateLimits != nil {
+		validator.RateLimits = *policy.Spec.RateLimits
+	}
+	if policy.Spec.CORS != nil {
+		validator.CORS = *policy.Spec.CORS
+	}
+	if policy.Spec.Authorization != nil {
+		validator.Authorization = *policy.Spec.Authorization
+	}
+	if policy.Spec.TLS != nil {
+		validator.TLS = *policy.Spec.TLS
+	}
+
+	return validator
+}
+
+func enforcementMode(policy *HTTPProxyValidationPolicy) EnforcementMode {
+	if policy == nil || policy.Spec.EnforcementMode == "" {
+		return EnforcementModeEnforce
+	}
+	return policy.Spec.EnforcementMode
+}
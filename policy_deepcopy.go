@@ -0,0 +1,222 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func (in *HTTPProxyValidationPolicy) DeepCopyInto(out *HTTPProxyValidationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+func (in *HTTPProxyValidationPolicy) DeepCopy() *HTTPProxyValidationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPProxyValidationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *HTTPProxyValidationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *HTTPProxyValidationPolicySpec) DeepCopyInto(out *HTTPProxyValidationPolicySpec) {
+	*out = *in
+	if in.TargetIngressClasses != nil {
+		out.TargetIngressClasses = make([]string, len(in.TargetIngressClasses))
+		copy(out.TargetIngressClasses, in.TargetIngressClasses)
+	}
+	if in.EnabledRules != nil {
+		out.EnabledRules = make([]string, len(in.EnabledRules))
+		copy(out.EnabledRules, in.EnabledRules)
+	}
+	if in.FqdnOwnership != nil {
+		out.FqdnOwnership = make([]FqdnOwnership, len(in.FqdnOwnership))
+		for i := range in.FqdnOwnership {
+			in.FqdnOwnership[i].DeepCopyInto(&out.FqdnOwnership[i])
+		}
+	}
+	if in.Limits != nil {
+		out.Limits = in.Limits.DeepCopy()
+	}
+	if in.HeaderPolicy != nil {
+		out.HeaderPolicy = in.HeaderPolicy.DeepCopy()
+	}
+	if in.RateLimits != nil {
+		out.RateLimits = in.RateLimits.DeepCopy()
+	}
+	if in.CORS != nil {
+		out.CORS = in.CORS.DeepCopy()
+	}
+	if in.Authorization != nil {
+		out.Authorization = in.Authorization.DeepCopy()
+	}
+	if in.TLS != nil {
+		out.TLS = in.TLS.DeepCopy()
+	}
+}
+
+func (in *HTTPProxyValidationPolicySpec) DeepCopy() *HTTPProxyValidationPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPProxyValidationPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *FqdnOwnership) DeepCopyInto(out *FqdnOwnership) {
+	*out = *in
+	if in.Namespaces != nil {
+		out.Namespaces = make([]string, len(in.Namespaces))
+		copy(out.Namespaces, in.Namespaces)
+	}
+}
+
+func (in *FqdnOwnership) DeepCopy() *FqdnOwnership {
+	if in == nil {
+		return nil
+	}
+	out := new(FqdnOwnership)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *Limits) DeepCopyInto(out *Limits) {
+	*out = *in
+}
+
+func (in *Limits) DeepCopy() *Limits {
+	if in == nil {
+		return nil
+	}
+	out := new(Limits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *HeaderPolicy) DeepCopyInto(out *HeaderPolicy) {
+	*out = *in
+	if in.ProtectedHeaders != nil {
+		out.ProtectedHeaders = make([]string, len(in.ProtectedHeaders))
+		copy(out.ProtectedHeaders, in.ProtectedHeaders)
+	}
+	if in.AllowedNamespaces != nil {
+		out.AllowedNamespaces = make([]string, len(in.AllowedNamespaces))
+		copy(out.AllowedNamespaces, in.AllowedNamespaces)
+	}
+}
+
+func (in *HeaderPolicy) DeepCopy() *HeaderPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *RateLimits) DeepCopyInto(out *RateLimits) {
+	*out = *in
+	if in.DescriptorKeys != nil {
+		out.DescriptorKeys = make([]string, len(in.DescriptorKeys))
+		copy(out.DescriptorKeys, in.DescriptorKeys)
+	}
+}
+
+func (in *RateLimits) DeepCopy() *RateLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *CORSRestrictions) DeepCopyInto(out *CORSRestrictions) {
+	*out = *in
+	if in.ProductionNamespaceSelector != nil {
+		out.ProductionNamespaceSelector = in.ProductionNamespaceSelector.DeepCopy()
+	}
+}
+
+func (in *CORSRestrictions) DeepCopy() *CORSRestrictions {
+	if in == nil {
+		return nil
+	}
+	out := new(CORSRestrictions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *AuthorizationRestrictions) DeepCopyInto(out *AuthorizationRestrictions) {
+	*out = *in
+	if in.ForbidDisablingNamespaceSelector != nil {
+		out.ForbidDisablingNamespaceSelector = in.ForbidDisablingNamespaceSelector.DeepCopy()
+	}
+}
+
+func (in *AuthorizationRestrictions) DeepCopy() *AuthorizationRestrictions {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthorizationRestrictions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *TLSRestrictions) DeepCopyInto(out *TLSRestrictions) {
+	*out = *in
+	if in.ExemptNamespaces != nil {
+		out.ExemptNamespaces = make([]string, len(in.ExemptNamespaces))
+		copy(out.ExemptNamespaces, in.ExemptNamespaces)
+	}
+	if in.ExemptFqdns != nil {
+		out.ExemptFqdns = make([]string, len(in.ExemptFqdns))
+		copy(out.ExemptFqdns, in.ExemptFqdns)
+	}
+}
+
+func (in *TLSRestrictions) DeepCopy() *TLSRestrictions {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSRestrictions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *HTTPProxyValidationPolicyList) DeepCopyInto(out *HTTPProxyValidationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]HTTPProxyValidationPolicy, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *HTTPProxyValidationPolicyList) DeepCopy() *HTTPProxyValidationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPProxyValidationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *HTTPProxyValidationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/client-go/rest"
+)
+
+type TestPolicySource struct {
+	policy *HTTPProxyValidationPolicy
+}
+
+func (ps *TestPolicySource) Current() *HTTPProxyValidationPolicy {
+	return ps.policy
+}
+
+func TestApplyPolicy(t *testing.T) {
+	defaults := Validator{
+		TargetIngressClasses: []string{"contour"},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *HTTPProxyValidationPolicy
+		expected Validator
+	}{
+		{
+			"no policy",
+			nil,
+			defaults,
+		},
+		{
+			"empty policy keeps defaults",
+			&HTTPProxyValidationPolicy{},
+			defaults,
+		},
+		{
+			"policy overrides defaults",
+			&HTTPProxyValidationPolicy{
+				Spec: HTTPProxyValidationPolicySpec{
+					TargetIngressClasses: []string{"internal", "external"},
+					EnabledRules:         []string{ruleFqdnOwnership},
+					FqdnOwnership: []FqdnOwnership{
+						{Fqdn: "*.example.com", Namespaces: []string{"team-a"}},
+					},
+					Limits: &Limits{MaxRetries: 3},
+				},
+			},
+			Validator{
+				TargetIngressClasses: []string{"internal", "external"},
+				EnabledRules:         []string{ruleFqdnOwnership},
+				FqdnOwnership: []FqdnOwnership{
+					{Fqdn: "*.example.com", Namespaces: []string{"team-a"}},
+				},
+				Limits: Limits{MaxRetries: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyPolicy(defaults, tt.policy)
+			if diff := cmp.Diff(got, tt.expected); diff != "" {
+				t.Errorf("applyPolicy %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestEnforcementMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *HTTPProxyValidationPolicy
+		expected EnforcementMode
+	}{
+		{"no policy", nil, EnforcementModeEnforce},
+		{"unset", &HTTPProxyValidationPolicy{}, EnforcementModeEnforce},
+		{
+			"warn",
+			&HTTPProxyValidationPolicy{Spec: HTTPProxyValidationPolicySpec{EnforcementMode: EnforcementModeWarn}},
+			EnforcementModeWarn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enforcementMode(tt.policy); got != tt.expected {
+				t.Errorf("enforcementMode %s: got %s, want %s", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPolicyWatcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/apis/httpproxy-validation.io/v1alpha1/httpproxyvalidationpolicies" {
+			t.Errorf("unexpected request path: %s", req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if got := req.URL.Query().Get("fieldSelector"); got != "metadata.name=default" {
+			t.Errorf("unexpected field selector: %s", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if req.URL.Query().Get("watch") == "true" {
+			// Hold the watch open until the client goes away.
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-req.Context().Done()
+			return
+		}
+
+		_, _ = w.Write([]byte(`{
+	"apiVersion": "httpproxy-validation.io/v1alpha1",
+	"kind": "HTTPProxyValidationPolicyList",
+	"metadata": {"resourceVersion": "1"},
+	"items": [
+		{
+			"apiVersion": "httpproxy-validation.io/v1alpha1",
+			"kind": "HTTPProxyValidationPolicy",
+			"metadata": {"name": "default", "generation": 3, "resourceVersion": "1"},
+			"spec": {
+				"targetIngressClasses": ["internal"],
+				"enforcementMode": "Warn"
+			}
+		}
+	]
+}`))
+	}))
+	defer server.Close()
+
+	watcher, err := NewPolicyWatcher(&rest.Config{Host: server.URL}, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	synced := make(chan bool)
+	go func() { synced <- watcher.Run(stopCh) }()
+
+	select {
+	case ok := <-synced:
+		if !ok {
+			t.Fatal("policy watcher failed to sync")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for policy watcher to sync")
+	}
+
+	policy := watcher.Current()
+	if policy == nil {
+		t.Fatal("expected policy, got nil")
+	}
+
+	if policy.Generation != 3 {
+		t.Errorf("policy generation got %d, want 3", policy.Generation)
+	}
+
+	expected := HTTPProxyValidationPolicySpec{
+		TargetIngressClasses: []string{"internal"},
+		EnforcementMode:      EnforcementModeWarn,
+	}
+	if diff := cmp.Diff(policy.Spec, expected); diff != "" {
+		t.Errorf("policy spec: (-got +want)\n%s", diff)
+	}
+
+	watcher.setPolicy(nil)
+	if policy := watcher.Current(); policy != nil {
+		t.Errorf("expected policy to be removed, got %v", policy)
+	}
+}
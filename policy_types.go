@@ -0,0 +1,157 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var policyGroupVersion = schema.GroupVersion{
+	Group:   "httpproxy-validation.io",
+	Version: "v1alpha1",
+}
+
+const policyResource = "httpproxyvalidationpolicies"
+
+type EnforcementMode string
+
+const (
+	// EnforcementModeEnforce denies admission of proxies that fail validation.
+	EnforcementModeEnforce EnforcementMode = "Enforce"
+	// EnforcementModeWarn admits proxies that fail validation and returns the
+	// validation failures to the client as warnings.
+	EnforcementModeWarn EnforcementMode = "Warn"
+)
+
+// HTTPProxyValidationPolicy is a cluster scoped resource configuring the
+// validator at runtime.
+type HTTPProxyValidationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HTTPProxyValidationPolicySpec `json:"spec"`
+}
+
+type HTTPProxyValidationPolicySpec struct {
+	// TargetIngressClasses overrides the ingress classes the validator
+	// considers. The configured defaults are used when empty.
+	TargetIngressClasses []string `json:"targetIngressClasses,omitempty"`
+	// EnforcementMode controls whether failed validations deny admission.
+	// Defaults to Enforce.
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+	// EnabledRules restricts validation to the named rules. All rules are
+	// enabled when empty.
+	EnabledRules []string `json:"enabledRules,omitempty"`
+	// FqdnOwnership restricts which namespaces may claim an fqdn.
+	FqdnOwnership []FqdnOwnership `json:"fqdnOwnership,omitempty"`
+	// Limits caps the values proxies may configure. The configured defaults
+	// are used when not set.
+	Limits *Limits `json:"limits,omitempty"`
+	// HeaderPolicy restricts the headers proxies may set or remove.
+	HeaderPolicy *HeaderPolicy `json:"headerPolicy,omitempty"`
+	// RateLimits bounds the rate limit policies of proxies.
+	RateLimits *RateLimits `json:"rateLimits,omitempty"`
+	// CORS restricts the CORS policies of virtual hosts.
+	CORS *CORSRestrictions `json:"cors,omitempty"`
+	// Authorization restricts the external authorization of proxies.
+	Authorization *AuthorizationRestrictions `json:"authorization,omitempty"`
+	// TLS sets the TLS baseline of root proxies.
+	TLS *TLSRestrictions `json:"tls,omitempty"`
+}
+
+type FqdnOwnership struct {
+	// Fqdn is either an exact fqdn or a wildcard, such as *.example.com,
+	// matching a single leading label.
+	Fqdn string `json:"fqdn"`
+	// Namespaces allowed to create proxies for the fqdn.
+	Namespaces []string `json:"namespaces"`
+}
+
+// Limits are the maximum values proxies may configure, a zero value is
+// unlimited.
+type Limits struct {
+	MaxResponseTimeout       metav1.Duration `json:"maxResponseTimeout,omitempty"`
+	MaxIdleTimeout           metav1.Duration `json:"maxIdleTimeout,omitempty"`
+	MaxIdleConnectionTimeout metav1.Duration `json:"maxIdleConnectionTimeout,omitempty"`
+	MaxPerTryTimeout         metav1.Duration `json:"maxPerTryTimeout,omitempty"`
+	// MaxRetries caps the retry count of routes.
+	MaxRetries int64 `json:"maxRetries,omitempty"`
+	// MinHealthCheckInterval is the shortest interval between health checks.
+	MinHealthCheckInterval metav1.Duration `json:"minHealthCheckInterval,omitempty"`
+	// MaxHealthCheckThreshold caps the healthy and unhealthy threshold counts
+	// of health checks.
+	MaxHealthCheckThreshold int64 `json:"maxHealthCheckThreshold,omitempty"`
+	// MaxTrafficShiftPercent caps the percentage of the traffic of a route an
+	// update may move between its services. Unlimited when zero.
+	MaxTrafficShiftPercent int64 `json:"maxTrafficShiftPercent,omitempty"`
+}
+
+type HeaderPolicy struct {
+	// ProtectedHeaders may not be set or removed by proxies. Hop-by-hop and
+	// forwarding headers are protected when not set.
+	ProtectedHeaders []string `json:"protectedHeaders,omitempty"`
+	// AllowedNamespaces may set and remove protected headers.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+type RateLimits struct {
+	// MaxRequestsPerSecond caps local rate limits, converted to requests per
+	// second. Unlimited when zero.
+	MaxRequestsPerSecond int64 `json:"maxRequestsPerSecond,omitempty"`
+	// MaxBurst caps the burst of local rate limits. Unlimited when zero.
+	MaxBurst uint32 `json:"maxBurst,omitempty"`
+	// DescriptorKeys are the descriptor keys known to the global rate limit
+	// service. Any key is allowed when not set or empty.
+	DescriptorKeys []string `json:"descriptorKeys,omitempty"`
+}
+
+type CORSRestrictions struct {
+	// ProductionNamespaceSelector selects the namespaces whose proxies may
+	// not allow every origin.
+	ProductionNamespaceSelector *metav1.LabelSelector `json:"productionNamespaceSelector,omitempty"`
+	// MaxAge caps how long preflight responses may be cached. Unlimited when
+	// zero.
+	MaxAge metav1.Duration `json:"maxAge,omitempty"`
+}
+
+type AuthorizationRestrictions struct {
+	// ForbidDisablingNamespaceSelector selects the namespaces whose proxies
+	// may not disable external authorization.
+	ForbidDisablingNamespaceSelector *metav1.LabelSelector `json:"forbidDisablingNamespaceSelector,omitempty"`
+}
+
+type TLSRestrictions struct {
+	// RequireTLS denies root proxies without TLS unless they are exempt.
+	RequireTLS bool `json:"requireTLS,omitempty"`
+	// ExemptNamespaces may create root proxies without TLS.
+	ExemptNamespaces []string `json:"exemptNamespaces,omitempty"`
+	// ExemptFqdns are exact fqdns or wildcards, such as *.example.com, that
+	// may be served without TLS.
+	ExemptFqdns []string `json:"exemptFqdns,omitempty"`
+	// MinimumProtocolVersion is the lowest minimum TLS version, 1.2 or 1.3,
+	// root proxies may set. Any other value denies every proxy terminating
+	// TLS, unset allows both.
+	MinimumProtocolVersion string `json:"minimumProtocolVersion,omitempty"`
+	// RequireInsecureJustification denies routes setting permitInsecure
+	// unless the proxy explains why in an annotation.
+	RequireInsecureJustification bool `json:"requireInsecureJustification,omitempty"`
+	// ForbidPassthroughRoutes denies root proxies passing TLS through while
+	// also defining routes or includes.
+	ForbidPassthroughRoutes bool `json:"forbidPassthroughRoutes,omitempty"`
+}
+
+type HTTPProxyValidationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []HTTPProxyValidationPolicy `json:"items"`
+}
+
+func addPolicyTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(policyGroupVersion,
+		&HTTPProxyValidationPolicy{},
+		&HTTPProxyValidationPolicyList{},
+	)
+	metav1.AddToGroupVersion(scheme, policyGroupVersion)
+	return nil
+}
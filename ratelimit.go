@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const ruleRateLimitPolicy = "rate-limit-policy"
+
+// rateLimitUnits are the local rate limit units contour accepts, in seconds.
+var rateLimitUnits = map[string]int64{
+	"second": 1,
+	"minute": 60,
+	"hour":   3600,
+}
+
+// rateLimitDescriptorKey returns the key contour sends to the rate limit
+// service for the entry.
+func rateLimitDescriptorKey(entry contourv1.RateLimitDescriptorEntry) string {
+	switch {
+	case entry.GenericKey != nil:
+		if entry.GenericKey.Key == "" {
+			return "generic_key"
+		}
+		return entry.GenericKey.Key
+	case entry.RequestHeader != nil:
+		return entry.RequestHeader.DescriptorKey
+	case entry.RequestHeaderValueMatch != nil:
+		return "header_match"
+	case entry.RemoteAddress != nil:
+		return "remote_address"
+	}
+	return ""
+}
+
+func rateLimitDescriptorTypes(entry contourv1.RateLimitDescriptorEntry) int {
+	count := 0
+	for _, set := range []bool{entry.GenericKey != nil, entry.RequestHeader != nil, entry.RequestHeaderValueMatch != nil, entry.RemoteAddress != nil} {
+		if set {
+			count++
+		}
+	}
+	return count
+}
+
+func (v Validator) checkRateLimitPolicies(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	limits := v.RateLimits
+	checkPolicy := func(path string, policy *contourv1.RateLimitPolicy) {
+		if policy == nil {
+			return
+		}
+
+		if local := policy.Local; local != nil {
+			seconds, validUnit := rateLimitUnits[local.Unit]
+			if !validUnit {
+				field := path + ".local.unit"
+				invalid(field, "%s %q must be one of second, minute or hour", field, local.Unit)
+			}
+			if local.Requests == 0 {
+				field := path + ".local.requests"
+				invalid(field, "%s must be at least 1", field)
+			} else if validUnit && limits.MaxRequestsPerSecond > 0 && int64(local.Requests) > limits.MaxRequestsPerSecond*seconds {
+				field := path + ".local.requests"
+				invalid(field, "%s %d per %s exceeds the maximum of %d per second", field, local.Requests, local.Unit, limits.MaxRequestsPerSecond)
+			}
+			if limits.MaxBurst > 0 && local.Burst > limits.MaxBurst {
+				field := path + ".local.burst"
+				invalid(field, "%s %d exceeds the maximum of %d", field, local.Burst, limits.MaxBurst)
+			}
+			if code := local.ResponseStatusCode; code != 0 && (code < 400 || code > 599) {
+				field := path + ".local.responseStatusCode"
+				invalid(field, "%s %d must be between 400 and 599", field, code)
+			}
+			for k, header := range local.ResponseHeadersToAdd {
+				if len(validation.IsHTTPHeaderName(header.Name)) > 0 {
+					field := fmt.Sprintf("%s.local.responseHeadersToAdd[%d].name", path, k)
+					invalid(field, "%s %q is not a valid header name", field, header.Name)
+				}
+			}
+		}
+
+		if global := policy.Global; global != nil {
+			if global.Disabled && len(global.Descriptors) > 0 {
+				field := path + ".global.descriptors"
+				invalid(field, "%s are ignored when global rate limiting is disabled", field)
+			}
+			for j, descriptor := range global.Descriptors {
+				if len(descriptor.Entries) == 0 {
+					field := fmt.Sprintf("%s.global.descriptors[%d].entries", path, j)
+					invalid(field, "%s is required", field)
+				}
+				for k, entry := range descriptor.Entries {
+					field := fmt.Sprintf("%s.global.descriptors[%d].entries[%d]", path, j, k)
+					if rateLimitDescriptorTypes(entry) != 1 {
+						invalid(field, "%s must set exactly one descriptor", field)
+						continue
+					}
+					if header := entry.RequestHeader; header != nil && (header.HeaderName == "" || header.DescriptorKey == "") {
+						invalid(field, "%s.requestHeader requires headerName and descriptorKey", field)
+						continue
+					}
+					key := rateLimitDescriptorKey(entry)
+					if len(limits.DescriptorKeys) > 0 && !slices.Contains(limits.DescriptorKeys, key) {
+						invalid(field, "%s: descriptor key %q is not known to the rate limit service", field, key)
+					}
+				}
+			}
+		}
+	}
+
+	if proxy.Spec.VirtualHost != nil {
+		checkPolicy("virtualhost.rateLimitPolicy", proxy.Spec.VirtualHost.RateLimitPolicy)
+	}
+	for i, route := range proxy.Spec.Routes {
+		checkPolicy(fmt.Sprintf("routes[%d].rateLimitPolicy", i), route.RateLimitPolicy)
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
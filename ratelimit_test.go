@@ -0,0 +1,184 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsValidProxyRateLimitPolicy(t *testing.T) {
+	limits := RateLimits{
+		MaxRequestsPerSecond: 100,
+		MaxBurst:             50,
+		DescriptorKeys:       []string{"generic_key", "remote_address", "tenant"},
+	}
+
+	tests := []struct {
+		name        string
+		virtualHost *contourv1.RateLimitPolicy
+		route       *contourv1.RateLimitPolicy
+		expected    ValidationResponse
+	}{
+		{
+			"valid policies",
+			&contourv1.RateLimitPolicy{
+				Local: &contourv1.LocalRateLimitPolicy{Requests: 6000, Unit: "minute", Burst: 50},
+				Global: &contourv1.GlobalRateLimitPolicy{
+					Descriptors: []contourv1.RateLimitDescriptor{{
+						Entries: []contourv1.RateLimitDescriptorEntry{
+							{GenericKey: &contourv1.GenericKeyDescriptor{Value: "foo"}},
+							{RemoteAddress: &contourv1.RemoteAddressDescriptor{}},
+						},
+					}},
+				},
+			},
+			&contourv1.RateLimitPolicy{
+				Global: &contourv1.GlobalRateLimitPolicy{
+					Descriptors: []contourv1.RateLimitDescriptor{{
+						Entries: []contourv1.RateLimitDescriptorEntry{
+							{RequestHeader: &contourv1.RequestHeaderDescriptor{HeaderName: "X-Tenant", DescriptorKey: "tenant"}},
+						},
+					}},
+				},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"invalid local rate limit",
+			nil,
+			&contourv1.RateLimitPolicy{
+				Local: &contourv1.LocalRateLimitPolicy{
+					Requests:             0,
+					Unit:                 "day",
+					Burst:                1000,
+					ResponseStatusCode:   200,
+					ResponseHeadersToAdd: []contourv1.HeaderValue{{Name: "retry after", Value: "1"}},
+				},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].rateLimitPolicy.local.unit "day" must be one of second, minute or hour; routes[0].rateLimitPolicy.local.requests must be at least 1; routes[0].rateLimitPolicy.local.burst 1000 exceeds the maximum of 50; routes[0].rateLimitPolicy.local.responseStatusCode 200 must be between 400 and 599; routes[0].rateLimitPolicy.local.responseHeadersToAdd[0].name "retry after" is not a valid header name`,
+				Violations: []Violation{
+					{Rule: ruleRateLimitPolicy, Field: "spec.routes[0].rateLimitPolicy.local.unit", Message: `routes[0].rateLimitPolicy.local.unit "day" must be one of second, minute or hour`},
+					{Rule: ruleRateLimitPolicy, Field: "spec.routes[0].rateLimitPolicy.local.requests", Message: "routes[0].rateLimitPolicy.local.requests must be at least 1"},
+					{Rule: ruleRateLimitPolicy, Field: "spec.routes[0].rateLimitPolicy.local.burst", Message: "routes[0].rateLimitPolicy.local.burst 1000 exceeds the maximum of 50"},
+					{Rule: ruleRateLimitPolicy, Field: "spec.routes[0].rateLimitPolicy.local.responseStatusCode", Message: "routes[0].rateLimitPolicy.local.responseStatusCode 200 must be between 400 and 599"},
+					{Rule: ruleRateLimitPolicy, Field: "spec.routes[0].rateLimitPolicy.local.responseHeadersToAdd[0].name", Message: `routes[0].rateLimitPolicy.local.responseHeadersToAdd[0].name "retry after" is not a valid header name`},
+				},
+			},
+		},
+		{
+			"local rate limit above the maximum",
+			&contourv1.RateLimitPolicy{
+				Local: &contourv1.LocalRateLimitPolicy{Requests: 101, Unit: "second"},
+			},
+			nil,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.rateLimitPolicy.local.requests 101 per second exceeds the maximum of 100 per second",
+				Violations: []Violation{
+					{Rule: ruleRateLimitPolicy, Field: "spec.virtualhost.rateLimitPolicy.local.requests", Message: "virtualhost.rateLimitPolicy.local.requests 101 per second exceeds the maximum of 100 per second"},
+				},
+			},
+		},
+		{
+			"invalid global descriptors",
+			&contourv1.RateLimitPolicy{
+				Global: &contourv1.GlobalRateLimitPolicy{
+					Descriptors: []contourv1.RateLimitDescriptor{
+						{},
+						{
+							Entries: []contourv1.RateLimitDescriptorEntry{
+								{},
+								{GenericKey: &contourv1.GenericKeyDescriptor{Key: "unknown", Value: "foo"}},
+								{RequestHeader: &contourv1.RequestHeaderDescriptor{HeaderName: "X-Tenant"}},
+								{RequestHeaderValueMatch: &contourv1.RequestHeaderValueMatchDescriptor{Value: "foo"}},
+							},
+						},
+					},
+				},
+			},
+			&contourv1.RateLimitPolicy{
+				Global: &contourv1.GlobalRateLimitPolicy{
+					Disabled: true,
+					Descriptors: []contourv1.RateLimitDescriptor{{
+						Entries: []contourv1.RateLimitDescriptorEntry{{RemoteAddress: &contourv1.RemoteAddressDescriptor{}}},
+					}},
+				},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: virtualhost.rateLimitPolicy.global.descriptors[0].entries is required; virtualhost.rateLimitPolicy.global.descriptors[1].entries[0] must set exactly one descriptor; virtualhost.rateLimitPolicy.global.descriptors[1].entries[1]: descriptor key "unknown" is not known to the rate limit service; virtualhost.rateLimitPolicy.global.descriptors[1].entries[2].requestHeader requires headerName and descriptorKey; virtualhost.rateLimitPolicy.global.descriptors[1].entries[3]: descriptor key "header_match" is not known to the rate limit service; routes[0].rateLimitPolicy.global.descriptors are ignored when global rate limiting is disabled`,
+				Violations: []Violation{
+					{Rule: ruleRateLimitPolicy, Field: "spec.virtualhost.rateLimitPolicy.global.descriptors[0].entries", Message: "virtualhost.rateLimitPolicy.global.descriptors[0].entries is required"},
+					{Rule: ruleRateLimitPolicy, Field: "spec.virtualhost.rateLimitPolicy.global.descriptors[1].entries[0]", Message: "virtualhost.rateLimitPolicy.global.descriptors[1].entries[0] must set exactly one descriptor"},
+					{Rule: ruleRateLimitPolicy, Field: "spec.virtualhost.rateLimitPolicy.global.descriptors[1].entries[1]", Message: `virtualhost.rateLimitPolicy.global.descriptors[1].entries[1]: descriptor key "unknown" is not known to the rate limit service`},
+					{Rule: ruleRateLimitPolicy, Field: "spec.virtualhost.rateLimitPolicy.global.descriptors[1].entries[2]", Message: "virtualhost.rateLimitPolicy.global.descriptors[1].entries[2].requestHeader requires headerName and descriptorKey"},
+					{Rule: ruleRateLimitPolicy, Field: "spec.virtualhost.rateLimitPolicy.global.descriptors[1].entries[3]", Message: `virtualhost.rateLimitPolicy.global.descriptors[1].entries[3]: descriptor key "header_match" is not known to the rate limit service`},
+					{Rule: ruleRateLimitPolicy, Field: "spec.routes[0].rateLimitPolicy.global.descriptors", Message: "routes[0].rateLimitPolicy.global.descriptors are ignored when global rate limiting is disabled"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleRateLimitPolicy},
+				RateLimits:   limits,
+			}
+
+			proxy := newRouteProxy(contourv1.Route{RateLimitPolicy: tc.route})
+			proxy.Spec.VirtualHost.RateLimitPolicy = tc.virtualHost
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyRateLimitPolicyAnyDescriptorKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		descriptorKeys []string
+	}{
+		{"descriptor keys not set", nil},
+		{"empty descriptor keys", []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleRateLimitPolicy},
+				RateLimits:   RateLimits{DescriptorKeys: tc.descriptorKeys},
+			}
+
+			proxy := newRouteProxy(contourv1.Route{
+				RateLimitPolicy: &contourv1.RateLimitPolicy{
+					Local: &contourv1.LocalRateLimitPolicy{Requests: 1000000, Unit: "second", Burst: 1000000},
+					Global: &contourv1.GlobalRateLimitPolicy{
+						Descriptors: []contourv1.RateLimitDescriptor{{
+							Entries: []contourv1.RateLimitDescriptorEntry{{GenericKey: &contourv1.GenericKeyDescriptor{Key: "anything", Value: "foo"}}},
+						}},
+					},
+				},
+			})
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Valid {
+				t.Errorf("expected a valid proxy without configured bounds, got %q", resp.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sserializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	conflictMarkerKey = "httpproxy-validation/conflict"
+
+	eventReasonConflict         = "FqdnConflict"
+	eventReasonConflictResolved = "FqdnConflictResolved"
+
+	// reconcileKey is the only key queued, since conflicts are evaluated
+	// across every proxy at once.
+	reconcileKey = "httpproxies"
+)
+
+// ConflictMarker selects how conflicting proxies are marked in addition to
+// the events recorded on them.
+type ConflictMarker string
+
+const (
+	ConflictMarkerNone       ConflictMarker = ""
+	ConflictMarkerLabel      ConflictMarker = "label"
+	ConflictMarkerAnnotation ConflictMarker = "annotation"
+)
+
+// ConflictReconciler watches HTTPProxies and reports the proxies that are in
+// conflict with each other, since contour owns their status.
+type ConflictReconciler struct {
+	Validator Validator
+	// Policy optionally provides a validation policy applied on top of the
+	// Validator for each reconcile.
+	Policy   PolicySource
+	Recorder record.EventRecorder
+	Marker   ConflictMarker
+	// Patcher updates the marker of proxies. Required unless Marker is
+	// ConflictMarkerNone.
+	Patcher ProxyPatcher
+
+	informer cache.SharedIndexInformer
+	queue    workqueue.RateLimitingInterface
+
+	mu sync.Mutex
+	// reported holds the message of the conflict last reported for each
+	// proxy so events are only recorded when it changes.
+	reported map[string]string
+}
+
+func NewConflictReconciler(config *rest.Config, resync time.Duration) (*ConflictReconciler, error) {
+	proxyConfig := rest.CopyConfig(config)
+	proxyConfig.GroupVersion = &contourv1.GroupVersion
+	proxyConfig.APIPath = "/apis"
+	proxyConfig.NegotiatedSerializer = k8sserializer.NewCodecFactory(runtimeScheme).WithoutConversion()
+
+	client, err := rest.RESTClientFor(proxyConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	listWatch := cache.NewListWatchFromClient(client, "httpproxies", metav1.NamespaceAll, fields.Everything())
+
+	cr := &ConflictReconciler{
+		informer: cache.NewSharedIndexInformer(listWatch, &contourv1.HTTPProxy{}, resync, cache.Indexers{}),
+		queue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+
+	enqueue := func(_ interface{}) {
+		cr.queue.Add(reconcileKey)
+	}
+	_, err = cr.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(_, obj interface{}) {
+			enqueue(obj)
+		},
+		DeleteFunc: enqueue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cr, nil
+}
+
+// Run starts the informer and reconciles every change until stopCh is closed.
+func (cr *ConflictReconciler) Run(stopCh <-chan struct{}) {
+	defer cr.queue.ShutDown()
+
+	go cr.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, cr.informer.HasSynced) {
+		slog.Error("Failed to sync HTTPProxy informer")
+		return
+	}
+
+	go func() {
+		for cr.processNext() {
+		}
+	}()
+	<-stopCh
+}
+
+func (cr *ConflictReconciler) processNext() bool {
+	key, shutdown := cr.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer cr.queue.Done(key)
+
+	var proxies []contourv1.HTTPProxy
+	for _, obj := range cr.informer.GetStore().List() {
+		if proxy, ok := obj.(*contourv1.HTTPProxy); ok {
+			proxies = append(proxies, *proxy)
+		}
+	}
+
+	if err := cr.Reconcile(proxies); err != nil {
+		slog.Error("Failed to reconcile HTTPProxy conflicts", "error", err.Error())
+		cr.queue.AddRateLimited(key)
+		return true
+	}
+	cr.queue.Forget(key)
+	return true
+}
+
+// Reconcile records events on the proxies whose conflicts changed and updates
+// their marker to match.
+func (cr *ConflictReconciler) Reconcile(proxies []contourv1.HTTPProxy) error {
+	var policy *HTTPProxyValidationPolicy
+	if cr.Policy != nil {
+		policy = cr.Policy.Current()
+	}
+	validator := applyPolicy(cr.Validator, policy)
+
+	conflicts := map[string]string{}
+	if validator.ruleEnabled(ruleFqdnConflict) {
+		// Only the conflicts are reported, so the other rules are not
+		// evaluated. The proxies are sorted for stable event messages.
+		var targetted []contourv1.HTTPProxy
+		for _, p := range proxies {
+			if validator.proxyMatchesTargetIngressClasses(p) {
+				targetted = append(targetted, p)
+			}
+		}
+		sort.Slice(targetted, func(i, j int) bool {
+			return proxyKey(targetted[i]) < proxyKey(targetted[j])
+		})
+
+		for _, group := range validator.fqdnConflictGroups(targetted) {
+			for _, p := range group.Proxies {
+				var others []string
+				for _, other := range group.Proxies {
+					if other != p {
+						others = append(others, other.Namespace+"/"+other.Name)
+					}
+				}
+				conflicts[p.Namespace+"/"+p.Name] = fmt.Sprintf("%s is also claimed by %s", group.Fqdn, strings.Join(others, ", "))
+			}
+		}
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.reported == nil {
+		cr.reported = map[string]string{}
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	for i := range proxies {
+		proxy := &proxies[i]
+		key := proxyKey(*proxy)
+		seen[key] = true
+
+		message, inConflict := conflicts[key]
+		previous, wasReported := cr.reported[key]
+		marked := cr.hasMarker(*proxy)
+
+		switch {
+		case inConflict:
+			if previous != message {
+				cr.Recorder.Event(proxy, corev1.EventTypeWarning, eventReasonConflict, message)
+				cr.reported[key] = message
+			}
+			if cr.Marker != ConflictMarkerNone && !marked {
+				if err := cr.patchMarker(*proxy, true); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		case wasReported || marked:
+			cr.Recorder.Event(proxy, corev1.EventTypeNormal, eventReasonConflictResolved, "fqdn conflict resolved")
+			delete(cr.reported, key)
+			if marked {
+				if err := cr.patchMarker(*proxy, false); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	for key := range cr.reported {
+		if !seen[key] {
+			delete(cr.reported, key)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to update conflict markers: %v", errs)
+	}
+	return nil
+}
+
+// onlyConflictMarkerChanged reports whether an update leaves everything the
+// rules look at unchanged except the conflict marker. The reconciler marks
+// proxies that are in conflict, so validating its patches would deny them.
+func onlyConflictMarkerChanged(old, proxy contourv1.HTTPProxy) bool {
+	withoutMarker := func(values map[string]string) map[string]string {
+		values = maps.Clone(values)
+		delete(values, conflictMarkerKey)
+		return values
+	}
+
+	return equality.Semantic.DeepEqual(old.Spec, proxy.Spec) &&
+		equality.Semantic.DeepEqual(withoutMarker(old.GetLabels()), withoutMarker(proxy.GetLabels())) &&
+		equality.Semantic.DeepEqual(withoutMarker(old.GetAnnotations()), withoutMarker(proxy.GetAnnotations()))
+}
+
+func (cr *ConflictReconciler) hasMarker(proxy contourv1.HTTPProxy) bool {
+	switch cr.Marker {
+	case ConflictMarkerLabel:
+		return proxy.GetLabels()[conflictMarkerKey] == "true"
+	case ConflictMarkerAnnotation:
+		return proxy.GetAnnotations()[conflictMarkerKey] == "true"
+	}
+	return false
+}
+
+// patchMarker sets or removes the marker with a merge patch, where a null
+// value deletes the key.
+func (cr *ConflictReconciler) patchMarker(proxy contourv1.HTTPProxy, conflict bool) error {
+	var value *string
+	if conflict {
+		value = new(string)
+		*value = "true"
+	}
+
+	field := "labels"
+	if cr.Marker == ConflictMarkerAnnotation {
+		field = "annotations"
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			field: map[string]*string{conflictMarkerKey: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return cr.Patcher.PatchHTTPProxy(proxy.Namespace, proxy.Name, patch)
+}
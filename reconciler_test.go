@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type TestPatcher struct {
+	patches []string
+	err     error
+}
+
+func (tp *TestPatcher) PatchHTTPProxy(namespace, name string, patch []byte) error {
+	tp.patches = append(tp.patches, namespace+"/"+name+" "+string(patch))
+	return tp.err
+}
+
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestConflictReconciler(t *testing.T) {
+	labelled := newTestProxy("team-b", "proxy", "foo.bar.com")
+	labelled.SetLabels(map[string]string{conflictMarkerKey: "true"})
+
+	tests := []struct {
+		name            string
+		marker          ConflictMarker
+		snapshots       [][]contourv1.HTTPProxy
+		expectedEvents  []string
+		expectedPatches []string
+	}{
+		{
+			"no conflicts",
+			ConflictMarkerNone,
+			[][]contourv1.HTTPProxy{
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.baz.com"),
+				},
+			},
+			nil,
+			nil,
+		},
+		{
+			"events are recorded once per conflict",
+			ConflictMarkerNone,
+			[][]contourv1.HTTPProxy{
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.bar.com"),
+				},
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.bar.com"),
+				},
+			},
+			[]string{
+				"Warning FqdnConflict foo.bar.com is also claimed by team-b/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-a/proxy",
+			},
+			nil,
+		},
+		{
+			"new conflicting proxies are reported again",
+			ConflictMarkerNone,
+			[][]contourv1.HTTPProxy{
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.bar.com"),
+				},
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.bar.com"),
+					newTestProxy("team-c", "proxy", "foo.bar.com"),
+				},
+			},
+			[]string{
+				"Warning FqdnConflict foo.bar.com is also claimed by team-b/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-a/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-b/proxy, team-c/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-a/proxy, team-c/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-a/proxy, team-b/proxy",
+			},
+			nil,
+		},
+		{
+			"resolved conflicts are reported",
+			ConflictMarkerNone,
+			[][]contourv1.HTTPProxy{
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.bar.com"),
+				},
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.baz.com"),
+				},
+			},
+			[]string{
+				"Warning FqdnConflict foo.bar.com is also claimed by team-b/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-a/proxy",
+				"Normal FqdnConflictResolved fqdn conflict resolved",
+				"Normal FqdnConflictResolved fqdn conflict resolved",
+			},
+			nil,
+		},
+		{
+			"conflicts are labelled",
+			ConflictMarkerLabel,
+			[][]contourv1.HTTPProxy{
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					labelled,
+				},
+			},
+			[]string{
+				"Warning FqdnConflict foo.bar.com is also claimed by team-b/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-a/proxy",
+			},
+			[]string{
+				`team-a/proxy {"metadata":{"labels":{"httpproxy-validation/conflict":"true"}}}`,
+			},
+		},
+		{
+			"conflicts are annotated",
+			ConflictMarkerAnnotation,
+			[][]contourv1.HTTPProxy{
+				{
+					newTestProxy("team-a", "proxy", "foo.bar.com"),
+					newTestProxy("team-b", "proxy", "foo.bar.com"),
+				},
+			},
+			[]string{
+				"Warning FqdnConflict foo.bar.com is also claimed by team-b/proxy",
+				"Warning FqdnConflict foo.bar.com is also claimed by team-a/proxy",
+			},
+			[]string{
+				`team-a/proxy {"metadata":{"annotations":{"httpproxy-validation/conflict":"true"}}}`,
+				`team-b/proxy {"metadata":{"annotations":{"httpproxy-validation/conflict":"true"}}}`,
+			},
+		},
+		{
+			"stale labels are removed",
+			ConflictMarkerLabel,
+			[][]contourv1.HTTPProxy{
+				{
+					newTestProxy("team-a", "proxy", "foo.baz.com"),
+					labelled,
+				},
+			},
+			[]string{
+				"Normal FqdnConflictResolved fqdn conflict resolved",
+			},
+			[]string{
+				`team-b/proxy {"metadata":{"labels":{"httpproxy-validation/conflict":null}}}`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(100)
+			patcher := &TestPatcher{}
+			reconciler := ConflictReconciler{
+				Recorder: recorder,
+				Marker:   tc.marker,
+				Patcher:  patcher,
+			}
+
+			for _, snapshot := range tc.snapshots {
+				if err := reconciler.Reconcile(snapshot); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if diff := cmp.Diff(recordedEvents(recorder), tc.expectedEvents); diff != "" {
+				t.Errorf("unexpected events (-got +want):\n%s", diff)
+			}
+			if diff := cmp.Diff(patcher.patches, tc.expectedPatches); diff != "" {
+				t.Errorf("unexpected patches (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConflictReconcilerPolicy(t *testing.T) {
+	recorder := record.NewFakeRecorder(100)
+	reconciler := ConflictReconciler{
+		Recorder: recorder,
+		Policy: &TestPolicySource{
+			&HTTPProxyValidationPolicy{
+				Spec: HTTPProxyValidationPolicySpec{
+					EnabledRules: []string{ruleFqdnOwnership},
+				},
+			},
+		},
+	}
+
+	err := reconciler.Reconcile([]contourv1.HTTPProxy{
+		newTestProxy("team-a", "proxy", "foo.bar.com"),
+		newTestProxy("team-b", "proxy", "foo.bar.com"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if events := recordedEvents(recorder); len(events) > 0 {
+		t.Errorf("expected no events with the fqdn-conflict rule disabled, got %v", events)
+	}
+}
+
+func TestConflictReconcilerPatchError(t *testing.T) {
+	reconciler := ConflictReconciler{
+		Recorder: record.NewFakeRecorder(100),
+		Marker:   ConflictMarkerLabel,
+		Patcher:  &TestPatcher{err: errors.New("patch failed")},
+	}
+
+	err := reconciler.Reconcile([]contourv1.HTTPProxy{
+		newTestProxy("team-a", "proxy", "foo.bar.com"),
+		newTestProxy("team-b", "proxy", "foo.bar.com"),
+	})
+	if err == nil {
+		t.Error("expected patch error")
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// ReplayResult is the outcome of replaying a single recorded AdmissionReview.
+type ReplayResult struct {
+	// Line is the line of the source the review was read from, starting at 1.
+	Line    int
+	Request *admissionv1.AdmissionRequest
+	// Recorded is the response stored alongside the request, nil when the
+	// review was recorded without one.
+	Recorded *admissionv1.AdmissionResponse
+	Response *admissionv1.AdmissionResponse
+}
+
+// replayDecision is the part of a response compared between the recorded and
+// replayed responses. UIDs and audit annotations are expected to differ.
+type replayDecision struct {
+	Allowed  bool
+	Message  string
+	Warnings []string
+}
+
+func newReplayDecision(resp *admissionv1.AdmissionResponse) replayDecision {
+	decision := replayDecision{
+		Allowed:  resp.Allowed,
+		Warnings: resp.Warnings,
+	}
+	if resp.Result != nil {
+		decision.Message = resp.Result.Message
+	}
+	return decision
+}
+
+// Diff reports how the replayed response differs from the recorded one. It is
+// empty when they match or nothing was recorded.
+func (r ReplayResult) Diff() string {
+	if r.Recorded == nil {
+		return ""
+	}
+	return cmp.Diff(newReplayDecision(r.Recorded), newReplayDecision(r.Response))
+}
+
+// ReplayReviews reads one AdmissionReview per line and serves each through
+// the handler, returning the replayed responses in order. Blank lines are
+// skipped.
+func ReplayReviews(r io.Reader, handler http.Handler) ([]ReplayResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var results []ReplayResult
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		result, err := replayReview(line, handler)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		result.Line = lineNumber
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func replayReview(data []byte, handler http.Handler) (ReplayResult, error) {
+	review := admissionv1.AdmissionReview{}
+	if _, _, err := serializer.Decode(data, nil, &review); err != nil {
+		return ReplayResult{}, err
+	}
+	if review.Request == nil {
+		return ReplayResult{}, fmt.Errorf("review has no request")
+	}
+
+	result := ReplayResult{
+		Request:  review.Request,
+		Recorded: review.Response,
+	}
+
+	review.Response = nil
+	body, err := json.Marshal(review)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		return ReplayResult{}, fmt.Errorf("handler returned status %d", recorder.Code)
+	}
+
+	replayed := admissionv1.AdmissionReview{}
+	if _, _, err := serializer.Decode(recorder.Body.Bytes(), nil, &replayed); err != nil {
+		return ReplayResult{}, err
+	}
+	if replayed.Response == nil {
+		return ReplayResult{}, fmt.Errorf("handler returned no response")
+	}
+	result.Response = replayed.Response
+
+	return result, nil
+}
+
+// replayCommand replays recorded AdmissionReviews against a snapshot of
+// resources and returns the process exit code.
+func replayCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: replay [flags] <reviews.jsonl|-> ...")
+		fs.PrintDefaults()
+	}
+
+	var ingressClasses, snapshot, namespace, kubeconfig, kubeContext, output string
+	var cluster, checkIngresses, checkHTTPRoutes, protectIncludedProxies, diff bool
+	fs.StringVar(&ingressClasses, "ingress-classes", "", "Comma separated list of ingress class names to validate against")
+	fs.StringVar(&snapshot, "snapshot", "", "Comma separated list of manifest files or directories the reviews are validated against")
+	fs.StringVar(&namespace, "namespace", "default", "Namespace of snapshot manifests that do not set one")
+	fs.BoolVar(&cluster, "cluster", false, "Merge the snapshot with the resources of a live cluster")
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig used with -cluster. Uses the default loading rules when empty")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context used with -cluster")
+	fs.BoolVar(&checkIngresses, "check-ingresses", false, "Detect hostname conflicts between HTTPProxies and Ingresses")
+	fs.BoolVar(&checkHTTPRoutes, "check-httproutes", false, "Detect hostname conflicts between HTTPProxies and Gateway API HTTPRoutes")
+	fs.BoolVar(&protectIncludedProxies, "protect-included-proxies", false, "Deny deleting HTTPProxies that are still included by another HTTPProxy")
+	fs.BoolVar(&diff, "diff", false, "Only report reviews whose response differs from the recorded response")
+	fs.StringVar(&output, "output", outputText, "Report format, one of text or json")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	if output != outputText && output != outputJSON {
+		fmt.Fprintf(stderr, "Unknown output format %q\n", output)
+		return exitError
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	var manifests []Manifest
+	if snapshot != "" {
+		var err error
+		manifests, err = ReadManifests(splitList(snapshot), stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to read snapshot: %s\n", err.Error())
+			return exitError
+		}
+	}
+
+	overlay := manifestStore(manifests, namespace)
+	var store Store = overlay
+	if cluster {
+		clusterStore, err := NewKubeconfigStore(kubeconfig, kubeContext)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to setup cluster store: %s\n", err.Error())
+			return exitError
+		}
+
+		store, err = mergeStores(overlay, clusterStore, checkIngresses, checkHTTPRoutes)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to read cluster resources: %s\n", err.Error())
+			return exitError
+		}
+	}
+
+	admissionHandler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store:                  store,
+			TargetIngressClasses:   splitList(ingressClasses),
+			CheckIngresses:         checkIngresses,
+			CheckHTTPRoutes:        checkHTTPRoutes,
+			ProtectIncludedProxies: protectIncludedProxies,
+		},
+	}
+	handler := AdmissionMiddleware(admissionHandler.ValidateContext)
+
+	exitCode := exitValid
+	for _, path := range paths {
+		results, err := replayPath(path, stdin, handler)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to replay %s: %s\n", path, err.Error())
+			return exitError
+		}
+
+		for _, result := range results {
+			changed := result.Diff() != ""
+			if changed {
+				exitCode = exitInvalid
+			}
+			if diff && !changed {
+				continue
+			}
+
+			if output == outputJSON {
+				err = writeReplayJSON(stdout, result)
+			} else {
+				err = writeReplayText(stdout, path, result, diff)
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "Failed to write report: %s\n", err.Error())
+				return exitError
+			}
+		}
+	}
+
+	return exitCode
+}
+
+func replayPath(path string, stdin io.Reader, handler http.Handler) ([]ReplayResult, error) {
+	if path == "-" {
+		return ReplayReviews(stdin, handler)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReplayReviews(f, handler)
+}
+
+func writeReplayText(w io.Writer, source string, result ReplayResult, diff bool) error {
+	req := result.Request
+	decision := "ALLOW"
+	if !result.Response.Allowed {
+		decision = "DENY"
+	}
+
+	fmt.Fprintf(w, "%s %s:%d %s %s %s/%s", decision, source, result.Line, req.Operation, req.Kind.Kind, req.Namespace, req.Name)
+	if result.Response.Result != nil && result.Response.Result.Message != "" {
+		fmt.Fprintf(w, ": %s", result.Response.Result.Message)
+	}
+	for _, warning := range result.Response.Warnings {
+		fmt.Fprintf(w, " (warning: %s)", warning)
+	}
+	_, err := fmt.Fprintln(w)
+
+	if diff {
+		_, err = fmt.Fprintf(w, "response differs (-recorded +replayed):\n%s", result.Diff())
+	}
+	return err
+}
+
+// writeReplayJSON writes the replayed review as a single line so the output
+// can be replayed again as a new baseline.
+func writeReplayJSON(w io.Writer, result ReplayResult) error {
+	review := admissionv1.AdmissionReview{
+		Request:  result.Request,
+		Response: result.Response,
+	}
+	review.SetGroupVersionKind(admissionv1.SchemeGroupVersion.WithKind("AdmissionReview"))
+
+	data, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
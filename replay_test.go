@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+// assertReplay replays the recorded reviews at path through the handler and
+// fails the test for every response that differs from the recording.
+func assertReplay(t *testing.T, path string, handler HTTPProxyAdmissionHandler) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := ReplayReviews(f, AdmissionMiddleware(handler.ValidateContext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if diff := result.Diff(); diff != "" {
+			t.Errorf("%s:%d: response for %s %s/%s changed (-recorded +replayed):\n%s", path, result.Line, result.Request.Operation, result.Request.Namespace, result.Request.Name, diff)
+		}
+	}
+}
+
+func replaySnapshotStore(t *testing.T) *MemoryStore {
+	t.Helper()
+
+	manifests, err := ReadManifests([]string{"testdata/replay/snapshot.yaml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manifestStore(manifests, "default")
+}
+
+func TestReplayRecordedReviews(t *testing.T) {
+	assertReplay(t, "testdata/replay/reviews.jsonl", HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: replaySnapshotStore(t),
+		},
+	})
+}
+
+func TestReplayReviews(t *testing.T) {
+	reviews, err := os.ReadFile("testdata/replay/reviews.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the existing proxy the recorded conflict is now allowed.
+	handler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: &MemoryStore{},
+		},
+	}
+	results, err := ReplayReviews(bytes.NewReader(reviews), AdmissionMiddleware(handler.ValidateContext))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var changed []int
+	for _, result := range results {
+		if result.Diff() != "" {
+			changed = append(changed, result.Line)
+		}
+	}
+	if diff := cmp.Diff(changed, []int{2}); diff != "" {
+		t.Errorf("unexpected changed lines (-got +want):\n%s", diff)
+	}
+}
+
+func TestReplayReviewsErrors(t *testing.T) {
+	handler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: &TestStore{
+				list: func() ([]contourv1.HTTPProxy, error) {
+					return nil, nil
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{
+			"invalid json",
+			"\n{not json\n",
+			"line 2: ",
+		},
+		{
+			"missing request",
+			`{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1"}`,
+			"line 1: review has no request",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReplayReviews(strings.NewReader(tc.input), AdmissionMiddleware(handler.ValidateContext))
+			if err == nil || !strings.HasPrefix(err.Error(), tc.expectedError) {
+				t.Errorf("expected error starting with %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestReplayCommand(t *testing.T) {
+	reviews := "testdata/replay/reviews.jsonl"
+	snapshot := "testdata/replay/snapshot.yaml"
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{
+			"all responses",
+			[]string{"-snapshot", snapshot, reviews},
+			exitValid,
+			"ALLOW " + reviews + ":1 CREATE HTTPProxy default/new\n" +
+				"DENY " + reviews + ":2 CREATE HTTPProxy default/conflict: conflict is in conflict with [existing]\n" +
+				"ALLOW " + reviews + ":3 DELETE HTTPProxy default/child\n",
+		},
+		{
+			"no differences",
+			[]string{"-snapshot", snapshot, "-diff", reviews},
+			exitValid,
+			"",
+		},
+		{
+			"differences against an empty snapshot",
+			[]string{"-diff", reviews},
+			exitInvalid,
+			"ALLOW " + reviews + ":2 CREATE HTTPProxy default/conflict\n" +
+				"response differs (-recorded +replayed):\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := replayCommand(tc.args, strings.NewReader(""), &stdout, &stderr)
+			if code != tc.expectedCode {
+				t.Errorf("expected exit code %d, got %d: %s", tc.expectedCode, code, stderr.String())
+			}
+			if !strings.HasPrefix(stdout.String(), tc.expectedOut) || (tc.expectedOut == "" && stdout.Len() > 0) {
+				t.Errorf("unexpected output (-got +want):\n%s", cmp.Diff(stdout.String(), tc.expectedOut))
+			}
+		})
+	}
+}
+
+func TestReplayCommandJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := replayCommand([]string{"-snapshot", "testdata/replay/snapshot.yaml", "-output", "json", "testdata/replay/reviews.jsonl"}, nil, &stdout, &stderr)
+	if code != exitValid {
+		t.Fatalf("expected exit code %d, got %d: %s", exitValid, code, stderr.String())
+	}
+
+	// The JSON output is itself a recording that replays without differences.
+	handler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: replaySnapshotStore(t),
+		},
+	}
+	results, err := ReplayReviews(&stdout, AdmissionMiddleware(handler.ValidateContext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 replayed reviews, got %d", len(results))
+	}
+	for _, result := range results {
+		if diff := result.Diff(); diff != "" {
+			t.Errorf("line %d changed (-recorded +replayed):\n%s", result.Line, diff)
+		}
+	}
+}
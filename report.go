@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputSARIF = "sarif"
+	outputJUnit = "junit"
+)
+
+var reportWriters = map[string]func(io.Writer, []LintResult) error{
+	outputText:  writeTextReport,
+	outputJSON:  writeJSONReport,
+	outputSARIF: writeSARIFReport,
+	outputJUnit: writeJUnitReport,
+}
+
+// reportViolations returns the violations of a result, falling back to the
+// reason when the validator did not report individual violations.
+func reportViolations(r LintResult) []Violation {
+	if r.Response.Valid {
+		return nil
+	}
+	if len(r.Response.Violations) > 0 {
+		return r.Response.Violations
+	}
+	return []Violation{{Message: r.Response.Reason}}
+}
+
+func lintExitCode(results []LintResult) int {
+	for _, r := range results {
+		if !r.Response.Valid {
+			return exitInvalid
+		}
+	}
+	return exitValid
+}
+
+func writeTextReport(w io.Writer, results []LintResult) error {
+	invalid := 0
+	for _, r := range results {
+		if r.Response.Valid {
+			continue
+		}
+		invalid++
+		fmt.Fprintf(w, "FAIL %s:%d %s/%s: %s\n", r.Manifest.Source, r.Manifest.Line, r.Proxy.Namespace, r.Proxy.Name, r.Response.Reason)
+	}
+
+	_, err := fmt.Fprintf(w, "%d HTTPProxies checked, %d invalid\n", len(results), invalid)
+	return err
+}
+
+type jsonReport struct {
+	Checked int          `json:"checked"`
+	Invalid int          `json:"invalid"`
+	Results []jsonResult `json:"results"`
+}
+
+type jsonResult struct {
+	Source     string          `json:"source"`
+	Line       int             `json:"line"`
+	Namespace  string          `json:"namespace"`
+	Name       string          `json:"name"`
+	Valid      bool            `json:"valid"`
+	Violations []jsonViolation `json:"violations,omitempty"`
+}
+
+type jsonViolation struct {
+	Violation
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func writeJSONReport(w io.Writer, results []LintResult) error {
+	report := jsonReport{
+		Checked: len(results),
+		Results: []jsonResult{},
+	}
+
+	for _, r := range results {
+		result := jsonResult{
+			Source:    r.Manifest.Source,
+			Line:      r.Manifest.Line,
+			Namespace: r.Proxy.Namespace,
+			Name:      r.Proxy.Name,
+			Valid:     r.Response.Valid,
+		}
+		if !r.Response.Valid {
+			report.Invalid++
+		}
+		for _, violation := range reportViolations(r) {
+			line, column := r.Manifest.FieldPosition(violation.Field)
+			result.Violations = append(result.Violations, jsonViolation{violation, line, column})
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// SARIF 2.1.0 types covering the subset of the format used by the report.
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+func writeSARIFReport(w io.Writer, results []LintResult) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "httpproxy-validation",
+				InformationURI: "https://github.com/seth-epps/httpproxy-validation",
+				Rules:          []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	for _, r := range results {
+		for _, violation := range reportViolations(r) {
+			ruleID := violation.Rule
+			if ruleID == "" {
+				ruleID = "validation"
+			}
+			if !slices.Contains(run.Tool.Driver.Rules, sarifRule{ruleID}) {
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ruleID})
+			}
+
+			line, column := r.Manifest.FieldPosition(violation.Field)
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  ruleID,
+				Level:   "error",
+				Message: sarifMessage{fmt.Sprintf("%s/%s: %s", r.Proxy.Namespace, r.Proxy.Name, violation.Message)},
+				Locations: []sarifLocation{
+					{
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{URI: r.Manifest.Source},
+							Region:           sarifRegion{StartLine: line, StartColumn: column},
+						},
+					},
+				},
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnitReport(w io.Writer, results []LintResult) error {
+	suite := junitTestSuite{
+		Name:  "httpproxy-validation",
+		Tests: len(results),
+	}
+
+	for _, r := range results {
+		testCase := junitTestCase{
+			ClassName: r.Manifest.Source,
+			Name:      r.Proxy.Namespace + "/" + r.Proxy.Name,
+		}
+		// JUnit consumers expect at most one failure per testcase, so the
+		// violations of a proxy are reported together.
+		var messages, rules, locations []string
+		for _, violation := range reportViolations(r) {
+			line, _ := r.Manifest.FieldPosition(violation.Field)
+			messages = append(messages, violation.Message)
+			if !slices.Contains(rules, violation.Rule) {
+				rules = append(rules, violation.Rule)
+			}
+			locations = append(locations, fmt.Sprintf("%s:%d %s", r.Manifest.Source, line, violation.Field))
+		}
+		if len(messages) > 0 {
+			testCase.Failure = &junitFailure{
+				Message: strings.Join(messages, "; "),
+				Type:    strings.Join(rules, ","),
+				Text:    strings.Join(locations, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
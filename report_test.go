@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const testReportManifest = `apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: proxy1
+spec:
+  virtualhost:
+    fqdn: foo.bar.com
+  routes:
+  - services:
+    - name: web
+      port: 80
+    - name: api
+      port: 8080
+`
+
+func newTestReportResults() []LintResult {
+	valid := contourv1.HTTPProxy{}
+	valid.SetNamespace("default")
+	valid.SetName("proxy0")
+
+	invalid := contourv1.HTTPProxy{}
+	invalid.SetNamespace("default")
+	invalid.SetName("proxy1")
+
+	return []LintResult{
+		{
+			Manifest: Manifest{Source: "proxies.yaml", Line: 1},
+			Proxy:    valid,
+			Response: ValidationResponse{Valid: true},
+		},
+		{
+			Manifest: Manifest{Source: "proxies.yaml", Line: 5, Raw: []byte(testReportManifest)},
+			Proxy:    invalid,
+			Response: ValidationResponse{
+				Valid:  false,
+				Reason: "proxy1 is in conflict with [proxy2]; port is not allowed",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "proxy1 is in conflict with [proxy2]"},
+					{Rule: "example", Field: "spec.routes[0].services[1].port", Message: "port is not allowed"},
+				},
+			},
+		},
+	}
+}
+
+func TestFieldPosition(t *testing.T) {
+	manifest := Manifest{Source: "proxies.yaml", Line: 10, Raw: []byte(testReportManifest)}
+
+	tests := []struct {
+		field  string
+		line   int
+		column int
+	}{
+		{"spec.virtualhost.fqdn", 16, 5},
+		{"spec.routes[0].services[1].port", 22, 7},
+		{"spec.routes[0].services[1]", 21, 7},
+		{"spec.routes[0].timeoutPolicy.idle", 18, 5},
+		{"spec.routes[3]", 17, 3},
+		{"", 10, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			line, column := manifest.FieldPosition(tt.field)
+			if line != tt.line || column != tt.column {
+				t.Errorf("FieldPosition(%q) got %d:%d, want %d:%d", tt.field, line, column, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestFieldPositionDottedKey(t *testing.T) {
+	manifest := Manifest{Source: "proxies.yaml", Line: 1, Raw: []byte(`apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: proxy1
+  annotations:
+    example.com/owner: team-a
+    kubernetes.io/ingress.class: contour
+`)}
+
+	line, column := manifest.FieldPosition(`metadata.annotations["kubernetes.io/ingress.class"]`)
+	if line != 7 || column != 5 {
+		t.Errorf("FieldPosition got %d:%d, want 7:5", line, column)
+	}
+}
+
+func TestFieldSegments(t *testing.T) {
+	tests := []struct {
+		field    string
+		expected []string
+	}{
+		{"spec.routes[0].services[1][2].port", []string{"spec", "routes", "[0]", "services", "[1]", "[2]", "port"}},
+		{`metadata.annotations["kubernetes.io/ingress.class"]`, []string{"metadata", "annotations", "kubernetes.io/ingress.class"}},
+		{"metadata.labels['example.com/a]b']", []string{"metadata", "labels", "example.com/a]b"}},
+		{"metadata.annotations[httpproxy-validation/allow-shared-fqdn]", []string{"metadata", "annotations", "httpproxy-validation/allow-shared-fqdn"}},
+		{"spec.routes[", []string{"spec", "routes", ""}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if diff := cmp.Diff(fieldSegments(tt.field), tt.expected); diff != "" {
+				t.Errorf("fieldSegments: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeJSONReport(&out, newTestReportResults()); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := `{
+  "checked": 2,
+  "invalid": 1,
+  "results": [
+    {
+      "source": "proxies.yaml",
+      "line": 1,
+      "namespace": "default",
+      "name": "proxy0",
+      "valid": true
+    },
+    {
+      "source": "proxies.yaml",
+      "line": 5,
+      "namespace": "default",
+      "name": "proxy1",
+      "valid": false,
+      "violations": [
+        {
+          "rule": "fqdn-conflict",
+          "field": "spec.virtualhost.fqdn",
+          "message": "proxy1 is in conflict with [proxy2]",
+          "line": 11,
+          "column": 5
+        },
+        {
+          "rule": "example",
+          "field": "spec.routes[0].services[1].port",
+          "message": "port is not allowed",
+          "line": 17,
+          "column": 7
+        }
+      ]
+    }
+  ]
+}
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("writeJSONReport: (-got +want)\n%s", diff)
+	}
+}
+
+func TestWriteSARIFReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeSARIFReport(&out, newTestReportResults()); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("report is not valid json: %s", err.Error())
+	}
+
+	expected := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "httpproxy-validation",
+						InformationURI: "https://github.com/seth-epps/httpproxy-validation",
+						Rules:          []sarifRule{{"fqdn-conflict"}, {"example"}},
+					},
+				},
+				Results: []sarifResult{
+					{
+						RuleID:  "fqdn-conflict",
+						Level:   "error",
+						Message: sarifMessage{"default/proxy1: proxy1 is in conflict with [proxy2]"},
+						Locations: []sarifLocation{
+							{PhysicalLocation: sarifPhysicalLocation{
+								ArtifactLocation: sarifArtifactLocation{URI: "proxies.yaml"},
+								Region:           sarifRegion{StartLine: 11, StartColumn: 5},
+							}},
+						},
+					},
+					{
+						RuleID:  "example",
+						Level:   "error",
+						Message: sarifMessage{"default/proxy1: port is not allowed"},
+						Locations: []sarifLocation{
+							{PhysicalLocation: sarifPhysicalLocation{
+								ArtifactLocation: sarifArtifactLocation{URI: "proxies.yaml"},
+								Region:           sarifRegion{StartLine: 17, StartColumn: 7},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(log, expected); diff != "" {
+		t.Errorf("writeSARIFReport: (-got +want)\n%s", diff)
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeJUnitReport(&out, newTestReportResults()); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="httpproxy-validation" tests="2" failures="1">
+    <testcase classname="proxies.yaml" name="default/proxy0"></testcase>
+    <testcase classname="proxies.yaml" name="default/proxy1">
+      <failure message="proxy1 is in conflict with [proxy2]; port is not allowed" type="fqdn-conflict,example">proxies.yaml:11 spec.virtualhost.fqdn&#xA;proxies.yaml:17 spec.routes[0].services[1].port</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("writeJUnitReport: (-got +want)\n%s", diff)
+	}
+}
+
+func TestLintCommandOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader(testReportManifest)
+
+	code := lintCommand([]string{"-output", "sarif", "-"}, stdin, &stdout, &stderr)
+	if code != exitValid {
+		t.Errorf("lintCommand: exit code got %d, want %d\nstderr: %s", code, exitValid, stderr.String())
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(stdout.Bytes(), &log); err != nil {
+		t.Fatalf("report is not valid json: %s", err.Error())
+	}
+
+	code = lintCommand([]string{"-output", "xml", "-"}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("lintCommand unknown output: exit code got %d, want %d", code, exitError)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+// sharedFqdnAnnotation opts a root proxy into sharing its fqdn with the other
+// proxies carrying the same group, e.g. during a migration between ingress
+// classes.
+const sharedFqdnAnnotation = "httpproxy-validation/allow-shared-fqdn"
+
+const ruleSharedFqdn = "shared-fqdn"
+
+func sharedFqdnGroup(proxy contourv1.HTTPProxy) string {
+	return proxy.GetAnnotations()[sharedFqdnAnnotation]
+}
+
+// sharingAllowed reports whether the namespace of the proxy may use the
+// sharing annotation for its fqdn, which requires an fqdn ownership entry
+// listing the namespace.
+func (v Validator) sharingAllowed(proxy contourv1.HTTPProxy) bool {
+	if proxy.Spec.VirtualHost == nil {
+		return false
+	}
+
+	for _, ownership := range v.FqdnOwnership {
+		if matchFqdn(ownership.Fqdn, proxy.Spec.VirtualHost.Fqdn) && slices.Contains(ownership.Namespaces, proxy.Namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharesFqdn reports whether both proxies opted into the same sharing group
+// and are allowed to.
+func (v Validator) sharesFqdn(a, b contourv1.HTTPProxy) bool {
+	group := sharedFqdnGroup(a)
+	return group != "" && sharedFqdnGroup(b) == group && v.sharingAllowed(a) && v.sharingAllowed(b)
+}
+
+func (v Validator) checkSharedFqdn(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	if sharedFqdnGroup(proxy) == "" {
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	if proxy.Spec.VirtualHost == nil {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: fmt.Sprintf("%s is not a root proxy and has no fqdn to share", proxy.Name),
+		}, nil
+	}
+
+	if !v.sharingAllowed(proxy) {
+		return ValidationResponse{
+			Valid:  false,
+			Reason: fmt.Sprintf("namespace %q is not allowed to share %s", proxy.Namespace, proxy.Spec.VirtualHost.Fqdn),
+		}, nil
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}, nil
+}
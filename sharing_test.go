@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func newSharedProxy(namespace, name, fqdn, group string) contourv1.HTTPProxy {
+	p := newTestProxy(namespace, name, fqdn)
+	if group != "" {
+		p.SetAnnotations(map[string]string{sharedFqdnAnnotation: group})
+	}
+	return p
+}
+
+func TestIsValidProxySharedFqdn(t *testing.T) {
+	ownership := []FqdnOwnership{
+		{Fqdn: "*.bar.com", Namespaces: []string{"team-a", "team-b"}},
+	}
+	sharedField := "metadata.annotations[" + sharedFqdnAnnotation + "]"
+
+	tests := []struct {
+		name             string
+		existing         contourv1.HTTPProxy
+		proxy            contourv1.HTTPProxy
+		expectedResponse ValidationResponse
+	}{
+		{
+			"same group",
+			newSharedProxy("team-a", "existing", "foo.bar.com", "migration"),
+			newSharedProxy("team-b", "new", "foo.bar.com", "migration"),
+			ValidationResponse{Valid: true},
+		},
+		{
+			"existing proxy without the annotation",
+			newSharedProxy("team-a", "existing", "foo.bar.com", ""),
+			newSharedProxy("team-b", "new", "foo.bar.com", "migration"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "new is in conflict with [existing]",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "new is in conflict with [existing]"},
+				},
+				Conflicts: []contourv1.HTTPProxy{newSharedProxy("team-a", "existing", "foo.bar.com", "")},
+			},
+		},
+		{
+			"different group",
+			newSharedProxy("team-a", "existing", "foo.bar.com", "blue"),
+			newSharedProxy("team-b", "new", "foo.bar.com", "green"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "new is in conflict with [existing]",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "new is in conflict with [existing]"},
+				},
+				Conflicts: []contourv1.HTTPProxy{newSharedProxy("team-a", "existing", "foo.bar.com", "blue")},
+			},
+		},
+		{
+			"namespace not allowed to share",
+			newSharedProxy("team-a", "existing", "foo.bar.com", "migration"),
+			newSharedProxy("team-c", "new", "foo.bar.com", "migration"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: `new is in conflict with [existing]; namespace "team-c" is not allowed to share foo.bar.com`,
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "new is in conflict with [existing]"},
+					{Rule: ruleSharedFqdn, Field: sharedField, Message: `namespace "team-c" is not allowed to share foo.bar.com`},
+				},
+				Conflicts: []contourv1.HTTPProxy{newSharedProxy("team-a", "existing", "foo.bar.com", "migration")},
+			},
+		},
+		{
+			"not a root proxy",
+			newSharedProxy("team-a", "existing", "foo.bar.com", "migration"),
+			newSharedProxy("team-b", "new", "", "migration"),
+			ValidationResponse{
+				Valid:  false,
+				Reason: "new is not a root proxy and has no fqdn to share",
+				Violations: []Violation{
+					{Rule: ruleSharedFqdn, Field: sharedField, Message: "new is not a root proxy and has no fqdn to share"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:         &MemoryStore{Proxies: []contourv1.HTTPProxy{tc.existing}},
+				EnabledRules:  []string{ruleFqdnConflict, ruleSharedFqdn},
+				FqdnOwnership: ownership,
+			}
+
+			resp, err := validator.IsValidProxy(tc.proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expectedResponse); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuditSharedFqdn(t *testing.T) {
+	validator := Validator{
+		Store: &MemoryStore{Proxies: []contourv1.HTTPProxy{
+			newSharedProxy("team-a", "blue", "foo.bar.com", "migration"),
+			newSharedProxy("team-b", "green", "foo.bar.com", "migration"),
+			newSharedProxy("team-a", "one", "baz.bar.com", "migration"),
+			newSharedProxy("team-b", "two", "baz.bar.com", ""),
+		}},
+		EnabledRules: []string{ruleFqdnConflict},
+		FqdnOwnership: []FqdnOwnership{
+			{Fqdn: "*.bar.com", Namespaces: []string{"team-a", "team-b"}},
+		},
+	}
+
+	report, err := validator.Audit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ConflictGroup{
+		{
+			Fqdn: "baz.bar.com",
+			Proxies: []ProxyClass{
+				{Namespace: "team-a", Name: "one"},
+				{Namespace: "team-b", Name: "two"},
+			},
+		},
+	}
+	if diff := cmp.Diff(report.Conflicts, expected); diff != "" {
+		t.Errorf("Audit conflicts: (-got +want)\n%s", diff)
+	}
+}
@@ -2,17 +2,66 @@ package main
 
 import (
 	"context"
+	"net/http"
 
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 type Store interface {
 	ListHTTPProxies() ([]contourv1.HTTPProxy, error)
 }
 
+// IngressStore is implemented by stores that can list Ingress resources
+type IngressStore interface {
+	ListIngresses() ([]networkingv1.Ingress, error)
+}
+
+// HTTPRouteStore is implemented by stores that can list Gateway API HTTPRoutes
+type HTTPRouteStore interface {
+	ListHTTPRoutes() ([]gatewayv1beta1.HTTPRoute, error)
+}
+
+// RemoteProxyStore is implemented by stores that list the HTTPProxies of
+// remote clusters together with the cluster each was listed from.
+type RemoteProxyStore interface {
+	ListRemoteHTTPProxies() ([]ClusterProxy, error)
+}
+
+// NamespaceStore is implemented by stores that can look up Namespaces. A
+// missing Namespace is returned as nil without an error.
+type NamespaceStore interface {
+	GetNamespace(name string) (*corev1.Namespace, error)
+}
+
+// ServiceStore is implemented by stores that can look up Services. A missing
+// Service is returned as nil without an error.
+type ServiceStore interface {
+	GetService(namespace, name string) (*corev1.Service, error)
+}
+
+// ExtensionServiceStore is implemented by stores that can look up
+// ExtensionServices. A missing ExtensionService is returned as nil without an
+// error.
+type ExtensionServiceStore interface {
+	GetExtensionService(namespace, name string) (*contourv1alpha1.ExtensionService, error)
+}
+
+// ProxyPatcher is implemented by stores that can update HTTPProxy metadata
+type ProxyPatcher interface {
+	PatchHTTPProxy(namespace, name string, patch []byte) error
+}
+
 type ClusterStore struct {
 	client rest.Interface
 }
@@ -26,7 +75,35 @@ func NewInClusterStore() (*ClusterStore, error) {
 	return NewClusterStore(config)
 }
 
+// NewKubeconfigStore creates a ClusterStore from a kubeconfig file and
+// context, falling back to the default loading rules when empty.
+func NewKubeconfigStore(kubeconfig, context string) (*ClusterStore, error) {
+	config, err := kubeconfigConfig(kubeconfig, context)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClusterStore(config)
+}
+
+func kubeconfigConfig(kubeconfig, context string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: context},
+	).ClientConfig()
+}
+
 func NewClusterStore(config *rest.Config) (*ClusterStore, error) {
+	// Trace the requests to the API server as children of the span of the
+	// context they are issued with.
+	config = rest.CopyConfig(config)
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return otelhttp.NewTransport(rt)
+	})
+
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
@@ -36,13 +113,22 @@ func NewClusterStore(config *rest.Config) (*ClusterStore, error) {
 	return &ClusterStore{restClient}, nil
 }
 
+// WithContext returns the store issuing its list requests with ctx.
+func (cs *ClusterStore) WithContext(ctx context.Context) Store {
+	return &contextClusterStore{cs, ctx}
+}
+
 func (cs *ClusterStore) ListHTTPProxies() ([]contourv1.HTTPProxy, error) {
+	return cs.listHTTPProxies(context.TODO())
+}
+
+func (cs *ClusterStore) listHTTPProxies(ctx context.Context) ([]contourv1.HTTPProxy, error) {
 	var proxyList contourv1.HTTPProxyList
 
 	err := cs.client.
 		Get().
 		AbsPath("/apis/projectcontour.io/v1/httpproxies").
-		Do(context.TODO()).
+		Do(ctx).
 		Into(&proxyList)
 
 	if err != nil {
@@ -51,3 +137,210 @@ func (cs *ClusterStore) ListHTTPProxies() ([]contourv1.HTTPProxy, error) {
 
 	return proxyList.Items, nil
 }
+
+// PatchHTTPProxy applies a JSON merge patch to the HTTPProxy.
+func (cs *ClusterStore) PatchHTTPProxy(namespace, name string, patch []byte) error {
+	return cs.client.
+		Patch(types.MergePatchType).
+		AbsPath("/apis/projectcontour.io/v1/namespaces", namespace, "httpproxies", name).
+		Body(patch).
+		Do(context.TODO()).
+		Error()
+}
+
+func (cs *ClusterStore) ListIngresses() ([]networkingv1.Ingress, error) {
+	return cs.listIngresses(context.TODO())
+}
+
+func (cs *ClusterStore) listIngresses(ctx context.Context) ([]networkingv1.Ingress, error) {
+	var ingressList networkingv1.IngressList
+
+	err := cs.client.
+		Get().
+		AbsPath("/apis/networking.k8s.io/v1/ingresses").
+		Do(ctx).
+		Into(&ingressList)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ingressList.Items, nil
+}
+
+// ListHTTPRoutes returns no routes when the Gateway API CRDs are not
+// installed in the cluster.
+func (cs *ClusterStore) ListHTTPRoutes() ([]gatewayv1beta1.HTTPRoute, error) {
+	return cs.listHTTPRoutes(context.TODO())
+}
+
+func (cs *ClusterStore) listHTTPRoutes(ctx context.Context) ([]gatewayv1beta1.HTTPRoute, error) {
+	var routeList gatewayv1beta1.HTTPRouteList
+
+	err := cs.client.
+		Get().
+		AbsPath("/apis/gateway.networking.k8s.io/v1beta1/httproutes").
+		Do(ctx).
+		Into(&routeList)
+
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return routeList.Items, nil
+}
+
+func (cs *ClusterStore) GetNamespace(name string) (*corev1.Namespace, error) {
+	return cs.getNamespace(context.TODO(), name)
+}
+
+func (cs *ClusterStore) getNamespace(ctx context.Context, name string) (*corev1.Namespace, error) {
+	var namespace corev1.Namespace
+
+	err := cs.client.
+		Get().
+		AbsPath("/api/v1/namespaces", name).
+		Do(ctx).
+		Into(&namespace)
+
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &namespace, nil
+}
+
+func (cs *ClusterStore) GetService(namespace, name string) (*corev1.Service, error) {
+	return cs.getService(context.TODO(), namespace, name)
+}
+
+func (cs *ClusterStore) getService(ctx context.Context, namespace, name string) (*corev1.Service, error) {
+	var service corev1.Service
+
+	err := cs.client.
+		Get().
+		AbsPath("/api/v1/namespaces", namespace, "services", name).
+		Do(ctx).
+		Into(&service)
+
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &service, nil
+}
+
+// GetExtensionService returns no ExtensionService when the contour CRDs are
+// not installed in the cluster.
+func (cs *ClusterStore) GetExtensionService(namespace, name string) (*contourv1alpha1.ExtensionService, error) {
+	return cs.getExtensionService(context.TODO(), namespace, name)
+}
+
+func (cs *ClusterStore) getExtensionService(ctx context.Context, namespace, name string) (*contourv1alpha1.ExtensionService, error) {
+	var extension contourv1alpha1.ExtensionService
+
+	err := cs.client.
+		Get().
+		AbsPath("/apis/projectcontour.io/v1alpha1/namespaces", namespace, "extensionservices", name).
+		Do(ctx).
+		Into(&extension)
+
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &extension, nil
+}
+
+// contextClusterStore is a ClusterStore bound to the context of a request.
+type contextClusterStore struct {
+	*ClusterStore
+	ctx context.Context
+}
+
+func (cs *contextClusterStore) ListHTTPProxies() ([]contourv1.HTTPProxy, error) {
+	return cs.listHTTPProxies(cs.ctx)
+}
+
+func (cs *contextClusterStore) ListIngresses() ([]networkingv1.Ingress, error) {
+	return cs.listIngresses(cs.ctx)
+}
+
+func (cs *contextClusterStore) ListHTTPRoutes() ([]gatewayv1beta1.HTTPRoute, error) {
+	return cs.listHTTPRoutes(cs.ctx)
+}
+
+func (cs *contextClusterStore) GetNamespace(name string) (*corev1.Namespace, error) {
+	return cs.getNamespace(cs.ctx, name)
+}
+
+func (cs *contextClusterStore) GetService(namespace, name string) (*corev1.Service, error) {
+	return cs.getService(cs.ctx, namespace, name)
+}
+
+func (cs *contextClusterStore) GetExtensionService(namespace, name string) (*contourv1alpha1.ExtensionService, error) {
+	return cs.getExtensionService(cs.ctx, namespace, name)
+}
+
+// MemoryStore serves resources from a fixed snapshot, such as manifests read
+// from disk.
+type MemoryStore struct {
+	Proxies    []contourv1.HTTPProxy
+	Ingresses  []networkingv1.Ingress
+	HTTPRoutes []gatewayv1beta1.HTTPRoute
+	Namespaces []corev1.Namespace
+	Services   []corev1.Service
+
+	ExtensionServices []contourv1alpha1.ExtensionService
+}
+
+func (ms *MemoryStore) ListHTTPProxies() ([]contourv1.HTTPProxy, error) {
+	return ms.Proxies, nil
+}
+
+func (ms *MemoryStore) ListIngresses() ([]networkingv1.Ingress, error) {
+	return ms.Ingresses, nil
+}
+
+func (ms *MemoryStore) ListHTTPRoutes() ([]gatewayv1beta1.HTTPRoute, error) {
+	return ms.HTTPRoutes, nil
+}
+
+func (ms *MemoryStore) GetNamespace(name string) (*corev1.Namespace, error) {
+	for i := range ms.Namespaces {
+		if ms.Namespaces[i].Name == name {
+			return &ms.Namespaces[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (ms *MemoryStore) GetService(namespace, name string) (*corev1.Service, error) {
+	for i := range ms.Services {
+		if ms.Services[i].Namespace == namespace && ms.Services[i].Name == name {
+			return &ms.Services[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (ms *MemoryStore) GetExtensionService(namespace, name string) (*contourv1alpha1.ExtensionService, error) {
+	for i := range ms.ExtensionServices {
+		if ms.ExtensionServices[i].Namespace == namespace && ms.ExtensionServices[i].Name == name {
+			return &ms.ExtensionServices[i], nil
+		}
+	}
+	return nil, nil
+}
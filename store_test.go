@@ -10,10 +10,12 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
 	fake "k8s.io/client-go/rest/fake"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 func TestListHTTPProxies(t *testing.T) {
@@ -124,3 +126,272 @@ func TestListHTTPProxiesError(t *testing.T) {
 		t.Errorf("Unexpected error: \n%s", errString)
 	}
 }
+
+func TestListIngresses(t *testing.T) {
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/apis/networking.k8s.io/v1/ingresses" {
+			t.Errorf("unexpected request path: %s", req.URL.Path)
+		}
+
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+
+		jsonOut := `{
+	"metadata": {},
+	"items": [
+		{
+			"metadata": {
+				"name": "ingress-1",
+				"namespace": "default",
+				"creationTimestamp": null
+			},
+			"spec": {
+				"rules": [{"host": "foo.bar.com"}]
+			},
+			"status": {
+				"loadBalancer": {}
+			}
+		}
+	]
+}`
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(bytes.NewReader([]byte(jsonOut)))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	expected := []networkingv1.Ingress{
+		newTestIngress("ingress-1", "", "foo.bar.com"),
+	}
+
+	store := ClusterStore{
+		c.RESTClient(),
+	}
+
+	resp, err := store.ListIngresses()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Errorf("ListIngresses: (-got +want)\n%s", diff)
+	}
+}
+
+func TestListHTTPRoutes(t *testing.T) {
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/apis/gateway.networking.k8s.io/v1beta1/httproutes" {
+			t.Errorf("unexpected request path: %s", req.URL.Path)
+		}
+
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+
+		jsonOut := `{
+	"metadata": {},
+	"items": [
+		{
+			"metadata": {
+				"name": "route-1",
+				"namespace": "default",
+				"creationTimestamp": null
+			},
+			"spec": {
+				"hostnames": ["foo.bar.com"]
+			},
+			"status": {
+				"parents": null
+			}
+		}
+	]
+}`
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(bytes.NewReader([]byte(jsonOut)))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	expected := []gatewayv1beta1.HTTPRoute{
+		newTestHTTPRoute("route-1", "foo.bar.com"),
+	}
+
+	store := ClusterStore{
+		c.RESTClient(),
+	}
+
+	resp, err := store.ListHTTPRoutes()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Errorf("ListHTTPRoutes: (-got +want)\n%s", diff)
+	}
+}
+
+func TestListHTTPRoutesNotInstalled(t *testing.T) {
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("404 page not found"))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	store := ClusterStore{
+		c.RESTClient(),
+	}
+
+	resp, err := store.ListHTTPRoutes()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(resp) != 0 {
+		t.Errorf("expected no routes, got %v", resp)
+	}
+}
+
+func TestPatchHTTPProxy(t *testing.T) {
+	patch := `{"metadata":{"labels":{"httpproxy-validation/conflict":"true"}}}`
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPatch {
+			t.Errorf("unexpected request method: %s", req.Method)
+		}
+		if req.URL.Path != "/apis/projectcontour.io/v1/namespaces/team-a/httpproxies/proxy-1" {
+			t.Errorf("unexpected request path: %s", req.URL.Path)
+		}
+		if contentType := req.Header.Get("Content-Type"); contentType != "application/merge-patch+json" {
+			t.Errorf("unexpected content type: %s", contentType)
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(body), patch); diff != "" {
+			t.Errorf("unexpected patch (-got +want):\n%s", diff)
+		}
+
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(bytes.NewReader([]byte(`{}`)))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	store := ClusterStore{
+		c.RESTClient(),
+	}
+
+	if err := store.PatchHTTPProxy("team-a", "proxy-1", []byte(patch)); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+}
+
+func TestGetNamespace(t *testing.T) {
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/api/v1/namespaces/prod" {
+			return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("404 page not found"))}, nil
+		}
+
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+		jsonOut := `{"metadata": {"name": "prod", "labels": {"environment": "production"}}}`
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(jsonOut))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	store := ClusterStore{
+		c.RESTClient(),
+	}
+
+	namespace, err := store.GetNamespace("prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if namespace == nil || namespace.Labels["environment"] != "production" {
+		t.Errorf("unexpected namespace: %v", namespace)
+	}
+
+	namespace, err = store.GetNamespace("missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if namespace != nil {
+		t.Errorf("expected no namespace, got %v", namespace)
+	}
+}
+
+func TestGetService(t *testing.T) {
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/api/v1/namespaces/auth/services/jwks" {
+			return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("404 page not found"))}, nil
+		}
+
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+		jsonOut := `{"metadata": {"name": "jwks", "namespace": "auth"}}`
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(jsonOut))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	store := ClusterStore{
+		c.RESTClient(),
+	}
+
+	service, err := store.GetService("auth", "jwks")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if service == nil || service.Name != "jwks" {
+		t.Errorf("unexpected service: %v", service)
+	}
+
+	service, err = store.GetService("auth", "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if service != nil {
+		t.Errorf("expected no service, got %v", service)
+	}
+}
+
+func TestGetExtensionService(t *testing.T) {
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/apis/projectcontour.io/v1alpha1/namespaces/auth/extensionservices/authz" {
+			return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("404 page not found"))}, nil
+		}
+
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+		jsonOut := `{"metadata": {"name": "authz", "namespace": "auth"}}`
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(jsonOut))}, nil
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	store := ClusterStore{
+		c.RESTClient(),
+	}
+
+	extension, err := store.GetExtensionService("auth", "authz")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if extension == nil || extension.Name != "authz" {
+		t.Errorf("unexpected extension service: %v", extension)
+	}
+
+	extension, err = store.GetExtensionService("auth", "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if extension != nil {
+		t.Errorf("expected no extension service, got %v", extension)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const (
+	ruleTimeoutPolicy = "timeout-policy"
+	ruleRetryPolicy   = "retry-policy"
+)
+
+// timeoutSetting is a timeout parsed the way contour parses it. A zero
+// duration that is not infinite uses the Envoy default.
+type timeoutSetting struct {
+	duration time.Duration
+	infinite bool
+}
+
+// parseTimeout parses a timeout with contour's semantics: empty or zero uses
+// the default, "infinity" and "infinite" disable the timeout and everything
+// else is a Go duration.
+func parseTimeout(value string) (timeoutSetting, error) {
+	if value == "" {
+		return timeoutSetting{}, nil
+	}
+	if value == "infinity" || value == "infinite" {
+		return timeoutSetting{infinite: true}, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return timeoutSetting{}, fmt.Errorf("unable to parse timeout %q", value)
+	}
+	if d < 0 {
+		return timeoutSetting{}, fmt.Errorf("timeout %q is negative", value)
+	}
+	return timeoutSetting{duration: d}, nil
+}
+
+// checkTimeout reports a problem with the timeout value, if any, when parsed
+// and compared against the maximum. A zero maximum is unlimited.
+func checkTimeout(value string, maximum time.Duration) string {
+	setting, err := parseTimeout(value)
+	if err != nil {
+		return err.Error()
+	}
+	if maximum == 0 {
+		return ""
+	}
+	if setting.infinite {
+		return fmt.Sprintf("infinite timeout exceeds the maximum of %s", maximum)
+	}
+	if setting.duration > maximum {
+		return fmt.Sprintf("timeout %s exceeds the maximum of %s", value, maximum)
+	}
+	return ""
+}
+
+func (v Validator) checkTimeoutPolicies(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	for i, route := range proxy.Spec.Routes {
+		policy := route.TimeoutPolicy
+		if policy == nil {
+			continue
+		}
+
+		timeouts := []struct {
+			name    string
+			value   string
+			maximum time.Duration
+		}{
+			{"response", policy.Response, v.Limits.MaxResponseTimeout.Duration},
+			{"idle", policy.Idle, v.Limits.MaxIdleTimeout.Duration},
+			{"idleConnection", policy.IdleConnection, v.Limits.MaxIdleConnectionTimeout.Duration},
+		}
+		for _, timeout := range timeouts {
+			if problem := checkTimeout(timeout.value, timeout.maximum); problem != "" {
+				field := fmt.Sprintf("routes[%d].timeoutPolicy.%s", i, timeout.name)
+				invalid(field, "%s: %s", field, problem)
+			}
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
+
+func (v Validator) checkRetryPolicies(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	for i, route := range proxy.Spec.Routes {
+		policy := route.RetryPolicy
+		if policy == nil {
+			continue
+		}
+
+		if maxRetries := v.Limits.MaxRetries; maxRetries > 0 && policy.NumRetries > maxRetries {
+			field := fmt.Sprintf("routes[%d].retryPolicy.count", i)
+			invalid(field, "%s %d exceeds the maximum of %d", field, policy.NumRetries, maxRetries)
+		}
+
+		if problem := checkTimeout(policy.PerTryTimeout, v.Limits.MaxPerTryTimeout.Duration); problem != "" {
+			field := fmt.Sprintf("routes[%d].retryPolicy.perTryTimeout", i)
+			invalid(field, "%s: %s", field, problem)
+		}
+
+		if len(policy.RetriableStatusCodes) > 0 && !slices.Contains(policy.RetryOn, "retriable-status-codes") {
+			field := fmt.Sprintf("routes[%d].retryPolicy.retriableStatusCodes", i)
+			invalid(field, "%s is only used with retriable-status-codes in retryOn", field)
+		}
+		for j, code := range policy.RetriableStatusCodes {
+			if code < 100 || code > 599 {
+				field := fmt.Sprintf("routes[%d].retryPolicy.retriableStatusCodes[%d]", i, j)
+				invalid(field, "%s %d is not an HTTP status code", field, code)
+			}
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRouteProxy(routes ...contourv1.Route) contourv1.HTTPProxy {
+	p := newTestProxy("default", "proxy-under-test", "foo.bar.com")
+	p.Spec.Routes = routes
+	return p
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      timeoutSetting
+		expectedError bool
+	}{
+		{"", timeoutSetting{}, false},
+		{"0s", timeoutSetting{}, false},
+		{"infinity", timeoutSetting{infinite: true}, false},
+		{"infinite", timeoutSetting{infinite: true}, false},
+		{"1m30s", timeoutSetting{duration: 90 * time.Second}, false},
+		{"1.5h", timeoutSetting{duration: 90 * time.Minute}, false},
+		{"10", timeoutSetting{}, true},
+		{"forever", timeoutSetting{}, true},
+		{"-1s", timeoutSetting{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			setting, err := parseTimeout(tc.value)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error %t, got %v", tc.expectedError, err)
+			}
+			if diff := cmp.Diff(setting, tc.expected, cmp.AllowUnexported(timeoutSetting{})); diff != "" {
+				t.Errorf("parseTimeout: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyTimeoutPolicy(t *testing.T) {
+	limits := Limits{
+		MaxResponseTimeout: metav1.Duration{Duration: time.Minute},
+		MaxIdleTimeout:     metav1.Duration{Duration: 5 * time.Minute},
+	}
+
+	tests := []struct {
+		name     string
+		limits   Limits
+		policy   *contourv1.TimeoutPolicy
+		expected ValidationResponse
+	}{
+		{
+			"no timeout policy",
+			limits,
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"within the limits",
+			limits,
+			&contourv1.TimeoutPolicy{Response: "30s", Idle: "5m", IdleConnection: "infinity"},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"unlimited",
+			Limits{},
+			&contourv1.TimeoutPolicy{Response: "infinity", Idle: "24h"},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"unparsable and above the limits",
+			limits,
+			&contourv1.TimeoutPolicy{Response: "2m", Idle: "infinite", IdleConnection: "1 hour"},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: routes[0].timeoutPolicy.response: timeout 2m exceeds the maximum of 1m0s; routes[0].timeoutPolicy.idle: infinite timeout exceeds the maximum of 5m0s; routes[0].timeoutPolicy.idleConnection: unable to parse timeout "1 hour"`,
+				Violations: []Violation{
+					{Rule: ruleTimeoutPolicy, Field: "spec.routes[0].timeoutPolicy.response", Message: "routes[0].timeoutPolicy.response: timeout 2m exceeds the maximum of 1m0s"},
+					{Rule: ruleTimeoutPolicy, Field: "spec.routes[0].timeoutPolicy.idle", Message: "routes[0].timeoutPolicy.idle: infinite timeout exceeds the maximum of 5m0s"},
+					{Rule: ruleTimeoutPolicy, Field: "spec.routes[0].timeoutPolicy.idleConnection", Message: `routes[0].timeoutPolicy.idleConnection: unable to parse timeout "1 hour"`},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleTimeoutPolicy},
+				Limits:       tc.limits,
+			}
+
+			resp, err := validator.IsValidProxy(newRouteProxy(contourv1.Route{TimeoutPolicy: tc.policy}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyRetryPolicy(t *testing.T) {
+	limits := Limits{
+		MaxRetries:       3,
+		MaxPerTryTimeout: metav1.Duration{Duration: 10 * time.Second},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *contourv1.RetryPolicy
+		expected ValidationResponse
+	}{
+		{
+			"no retry policy",
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"valid retry policy",
+			&contourv1.RetryPolicy{
+				NumRetries:           3,
+				PerTryTimeout:        "5s",
+				RetryOn:              []contourv1.RetryOn{"5xx", "retriable-status-codes"},
+				RetriableStatusCodes: []uint32{502, 503},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"retries disabled",
+			&contourv1.RetryPolicy{NumRetries: -1},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"too many retries and invalid per try timeout",
+			&contourv1.RetryPolicy{NumRetries: 10, PerTryTimeout: "1m"},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].retryPolicy.count 10 exceeds the maximum of 3; routes[0].retryPolicy.perTryTimeout: timeout 1m exceeds the maximum of 10s",
+				Violations: []Violation{
+					{Rule: ruleRetryPolicy, Field: "spec.routes[0].retryPolicy.count", Message: "routes[0].retryPolicy.count 10 exceeds the maximum of 3"},
+					{Rule: ruleRetryPolicy, Field: "spec.routes[0].retryPolicy.perTryTimeout", Message: "routes[0].retryPolicy.perTryTimeout: timeout 1m exceeds the maximum of 10s"},
+				},
+			},
+		},
+		{
+			"status codes without retriable-status-codes",
+			&contourv1.RetryPolicy{
+				RetryOn:              []contourv1.RetryOn{"5xx"},
+				RetriableStatusCodes: []uint32{503, 42},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].retryPolicy.retriableStatusCodes is only used with retriable-status-codes in retryOn; routes[0].retryPolicy.retriableStatusCodes[1] 42 is not an HTTP status code",
+				Violations: []Violation{
+					{Rule: ruleRetryPolicy, Field: "spec.routes[0].retryPolicy.retriableStatusCodes", Message: "routes[0].retryPolicy.retriableStatusCodes is only used with retriable-status-codes in retryOn"},
+					{Rule: ruleRetryPolicy, Field: "spec.routes[0].retryPolicy.retriableStatusCodes[1]", Message: "routes[0].retryPolicy.retriableStatusCodes[1] 42 is not an HTTP status code"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleRetryPolicy},
+				Limits:       limits,
+			}
+
+			resp, err := validator.IsValidProxy(newRouteProxy(contourv1.Route{RetryPolicy: tc.policy}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
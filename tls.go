@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+const ruleTLSPolicy = "tls-policy"
+
+// permitInsecureJustificationAnnotation explains why a proxy serves routes
+// over plain HTTP, required for routes setting permitInsecure.
+const permitInsecureJustificationAnnotation = "httpproxy-validation/permit-insecure-justification"
+
+// defaultTLSVersion is the minimum protocol version contour uses when a
+// virtual host does not set one.
+const defaultTLSVersion = "1.2"
+
+// tlsVersions are the protocol versions contour accepts, in order.
+var tlsVersions = []string{"1.2", "1.3"}
+
+func (v Validator) tlsExempt(proxy contourv1.HTTPProxy) bool {
+	if slices.Contains(v.TLS.ExemptNamespaces, proxy.Namespace) {
+		return true
+	}
+	return slices.ContainsFunc(v.TLS.ExemptFqdns, func(pattern string) bool {
+		return matchFqdn(pattern, proxy.Spec.VirtualHost.Fqdn)
+	})
+}
+
+func (v Validator) checkTLSPolicy(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	if vhost := proxy.Spec.VirtualHost; vhost != nil {
+		tls := vhost.TLS
+		switch {
+		case tls == nil:
+			if v.TLS.RequireTLS && !v.tlsExempt(proxy) {
+				invalid("virtualhost.tls", "virtualhost.tls is required")
+			}
+		case tls.Passthrough:
+			if v.TLS.ForbidPassthroughRoutes && (len(proxy.Spec.Routes) > 0 || len(proxy.Spec.Includes) > 0) {
+				invalid("virtualhost.tls.passthrough", "virtualhost.tls.passthrough: routes and includes are only served over plain HTTP when TLS is passed through")
+			}
+		default:
+			const field = "virtualhost.tls.minimumProtocolVersion"
+			version := tls.MinimumProtocolVersion
+			if version == "" {
+				version = defaultTLSVersion
+			}
+			// A policy minimum contour does not know fails closed rather than
+			// silently enforcing nothing.
+			minimum := v.TLS.MinimumProtocolVersion
+			switch {
+			case !slices.Contains(tlsVersions, version):
+				invalid(field, "%s %q is not supported, contour falls back to %s", field, version, defaultTLSVersion)
+			case minimum == "":
+			case !slices.Contains(tlsVersions, minimum):
+				invalid(field, "%s: the policy minimum %q is not one of %v", field, minimum, tlsVersions)
+			case slices.Index(tlsVersions, version) < slices.Index(tlsVersions, minimum):
+				invalid(field, "%s %s is below the minimum of %s", field, version, minimum)
+			}
+		}
+	}
+
+	if v.TLS.RequireInsecureJustification && proxy.GetAnnotations()[permitInsecureJustificationAnnotation] == "" {
+		for i, route := range proxy.Spec.Routes {
+			if route.PermitInsecure {
+				field := fmt.Sprintf("routes[%d].permitInsecure", i)
+				invalid(field, "%s requires a justification in the %s annotation", field, permitInsecureJustificationAnnotation)
+			}
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsValidProxyTLSPolicy(t *testing.T) {
+	restrictions := TLSRestrictions{
+		RequireTLS:                   true,
+		ExemptNamespaces:             []string{"legacy"},
+		ExemptFqdns:                  []string{"*.internal.bar.com"},
+		MinimumProtocolVersion:       "1.3",
+		RequireInsecureJustification: true,
+		ForbidPassthroughRoutes:      true,
+	}
+
+	tests := []struct {
+		name        string
+		namespace   string
+		fqdn        string
+		tls         *contourv1.TLS
+		annotations map[string]string
+		route       contourv1.Route
+		expected    ValidationResponse
+	}{
+		{
+			"tls 1.3",
+			"default",
+			"foo.bar.com",
+			&contourv1.TLS{SecretName: "tls", MinimumProtocolVersion: "1.3"},
+			nil,
+			contourv1.Route{},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"exempt namespace",
+			"legacy",
+			"foo.bar.com",
+			nil,
+			nil,
+			contourv1.Route{},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"exempt fqdn",
+			"default",
+			"app.internal.bar.com",
+			nil,
+			nil,
+			contourv1.Route{},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"justified permit insecure",
+			"default",
+			"foo.bar.com",
+			&contourv1.TLS{SecretName: "tls", MinimumProtocolVersion: "1.3"},
+			map[string]string{permitInsecureJustificationAnnotation: "ACME HTTP-01 challenges"},
+			contourv1.Route{PermitInsecure: true},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"without tls",
+			"default",
+			"foo.bar.com",
+			nil,
+			nil,
+			contourv1.Route{PermitInsecure: true},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.tls is required; routes[0].permitInsecure requires a justification in the httpproxy-validation/permit-insecure-justification annotation",
+				Violations: []Violation{
+					{Rule: ruleTLSPolicy, Field: "spec.virtualhost.tls", Message: "virtualhost.tls is required"},
+					{Rule: ruleTLSPolicy, Field: "spec.routes[0].permitInsecure", Message: "routes[0].permitInsecure requires a justification in the httpproxy-validation/permit-insecure-justification annotation"},
+				},
+			},
+		},
+		{
+			"default protocol version",
+			"default",
+			"foo.bar.com",
+			&contourv1.TLS{SecretName: "tls"},
+			nil,
+			contourv1.Route{},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.tls.minimumProtocolVersion 1.2 is below the minimum of 1.3",
+				Violations: []Violation{
+					{Rule: ruleTLSPolicy, Field: "spec.virtualhost.tls.minimumProtocolVersion", Message: "virtualhost.tls.minimumProtocolVersion 1.2 is below the minimum of 1.3"},
+				},
+			},
+		},
+		{
+			"unsupported protocol version",
+			"default",
+			"foo.bar.com",
+			&contourv1.TLS{SecretName: "tls", MinimumProtocolVersion: "1.1"},
+			nil,
+			contourv1.Route{},
+			ValidationResponse{
+				Valid:  false,
+				Reason: `proxy-under-test is invalid: virtualhost.tls.minimumProtocolVersion "1.1" is not supported, contour falls back to 1.2`,
+				Violations: []Violation{
+					{Rule: ruleTLSPolicy, Field: "spec.virtualhost.tls.minimumProtocolVersion", Message: `virtualhost.tls.minimumProtocolVersion "1.1" is not supported, contour falls back to 1.2`},
+				},
+			},
+		},
+		{
+			"passthrough with routes",
+			"default",
+			"foo.bar.com",
+			&contourv1.TLS{Passthrough: true},
+			nil,
+			contourv1.Route{},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: virtualhost.tls.passthrough: routes and includes are only served over plain HTTP when TLS is passed through",
+				Violations: []Violation{
+					{Rule: ruleTLSPolicy, Field: "spec.virtualhost.tls.passthrough", Message: "virtualhost.tls.passthrough: routes and includes are only served over plain HTTP when TLS is passed through"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleTLSPolicy},
+				TLS:          restrictions,
+			}
+
+			proxy := newTestProxy(tc.namespace, "proxy-under-test", tc.fqdn)
+			proxy.SetAnnotations(tc.annotations)
+			proxy.Spec.VirtualHost.TLS = tc.tls
+			proxy.Spec.Routes = []contourv1.Route{tc.route}
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyTLSPolicyInvalidMinimum(t *testing.T) {
+	for _, minimum := range []string{"1.2 ", "TLSv1.2"} {
+		t.Run(minimum, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleTLSPolicy},
+				TLS:          TLSRestrictions{MinimumProtocolVersion: minimum},
+			}
+
+			proxy := newTestProxy("default", "proxy-under-test", "foo.bar.com")
+			proxy.Spec.VirtualHost.TLS = &contourv1.TLS{SecretName: "tls", MinimumProtocolVersion: "1.3"}
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			message := fmt.Sprintf("virtualhost.tls.minimumProtocolVersion: the policy minimum %q is not one of [1.2 1.3]", minimum)
+			expected := ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: " + message,
+				Violations: []Violation{
+					{Rule: ruleTLSPolicy, Field: "spec.virtualhost.tls.minimumProtocolVersion", Message: message},
+				},
+			}
+			if diff := cmp.Diff(resp, expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyTLSPolicyDefaults(t *testing.T) {
+	validator := Validator{
+		Store:        &MemoryStore{},
+		EnabledRules: []string{ruleTLSPolicy},
+	}
+
+	proxy := newRouteProxy(contourv1.Route{PermitInsecure: true})
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Valid {
+		t.Errorf("expected a proxy without TLS to be valid when TLS is not required, got %q", resp.Reason)
+	}
+
+	proxy.Spec.VirtualHost.TLS = &contourv1.TLS{Passthrough: true}
+	resp, err = validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Valid {
+		t.Errorf("expected a passthrough proxy with routes to be valid by default, got %q", resp.Reason)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contourv1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const (
+	tracerName         = "github.com/seth-epps/httpproxy-validation-controller"
+	tracingServiceName = "httpproxy-validation"
+)
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// tracingEnabled reports whether the standard OTEL environment variables ask
+// for traces to be exported over OTLP.
+func tracingEnabled() bool {
+	switch os.Getenv("OTEL_TRACES_EXPORTER") {
+	case "otlp":
+		return true
+	case "none":
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// setupTracing installs a global tracer provider exporting spans over OTLP
+// HTTP. The exporter, sampler and resource are configured by the standard
+// OTEL environment variables. The returned function flushes and stops the
+// provider.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(tracingServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// endSpan records the error, if any, on the span before ending it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ContextStore is implemented by stores that can issue their requests with
+// the context of an admission request, so they are part of its trace.
+type ContextStore interface {
+	WithContext(context.Context) Store
+}
+
+func (v Validator) context() context.Context {
+	if v.Context == nil {
+		return context.Background()
+	}
+	return v.Context
+}
+
+// store returns the store bound to ctx when supported.
+func (v Validator) store(ctx context.Context) Store {
+	if contextStore, ok := v.Store.(ContextStore); ok {
+		return contextStore.WithContext(ctx)
+	}
+	return v.Store
+}
+
+// listHTTPProxies lists the proxies of the local cluster.
+func (v Validator) listHTTPProxies() ([]contourv1.HTTPProxy, error) {
+	ctx, span := tracer().Start(v.context(), "Store.ListHTTPProxies")
+	proxies, err := v.store(ctx).ListHTTPProxies()
+	span.SetAttributes(attribute.Int("count", len(proxies)))
+	endSpan(span, err)
+	return proxies, err
+}
+
+// listIngresses returns no Ingresses when the store cannot list them.
+func (v Validator) listIngresses() ([]networkingv1.Ingress, error) {
+	ingressStore, ok := v.Store.(IngressStore)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, span := tracer().Start(v.context(), "Store.ListIngresses")
+	if store, ok := v.store(ctx).(IngressStore); ok {
+		ingressStore = store
+	}
+	ingresses, err := ingressStore.ListIngresses()
+	span.SetAttributes(attribute.Int("count", len(ingresses)))
+	endSpan(span, err)
+	return ingresses, err
+}
+
+// listHTTPRoutes returns no HTTPRoutes when the store cannot list them.
+func (v Validator) listHTTPRoutes() ([]gatewayv1beta1.HTTPRoute, error) {
+	routeStore, ok := v.Store.(HTTPRouteStore)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, span := tracer().Start(v.context(), "Store.ListHTTPRoutes")
+	if store, ok := v.store(ctx).(HTTPRouteStore); ok {
+		routeStore = store
+	}
+	routes, err := routeStore.ListHTTPRoutes()
+	span.SetAttributes(attribute.Int("count", len(routes)))
+	endSpan(span, err)
+	return routes, err
+}
+
+// listRemoteHTTPProxies returns no proxies when the store does not know
+// remote clusters.
+func (v Validator) listRemoteHTTPProxies() ([]ClusterProxy, error) {
+	remoteStore, ok := v.Store.(RemoteProxyStore)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, span := tracer().Start(v.context(), "Store.ListRemoteHTTPProxies")
+	if store, ok := v.store(ctx).(RemoteProxyStore); ok {
+		remoteStore = store
+	}
+	proxies, err := remoteStore.ListRemoteHTTPProxies()
+	span.SetAttributes(attribute.Int("count", len(proxies)))
+	endSpan(span, err)
+	return proxies, err
+}
+
+// getNamespace returns no Namespace when the store cannot look them up.
+func (v Validator) getNamespace(name string) (*corev1.Namespace, error) {
+	namespaceStore, ok := v.Store.(NamespaceStore)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, span := tracer().Start(v.context(), "Store.GetNamespace")
+	if store, ok := v.store(ctx).(NamespaceStore); ok {
+		namespaceStore = store
+	}
+	namespace, err := namespaceStore.GetNamespace(name)
+	span.SetAttributes(attribute.String("namespace", name))
+	endSpan(span, err)
+	return namespace, err
+}
+
+// getService looks up a Service, ok is false when the store cannot look them
+// up.
+func (v Validator) getService(namespace, name string) (service *corev1.Service, ok bool, err error) {
+	serviceStore, ok := v.Store.(ServiceStore)
+	if !ok {
+		return nil, false, nil
+	}
+
+	ctx, span := tracer().Start(v.context(), "Store.GetService")
+	if store, ok := v.store(ctx).(ServiceStore); ok {
+		serviceStore = store
+	}
+	service, err = serviceStore.GetService(namespace, name)
+	span.SetAttributes(attribute.String("namespace", namespace), attribute.String("name", name))
+	endSpan(span, err)
+	return service, true, err
+}
+
+// getExtensionService looks up an ExtensionService, ok is false when the
+// store cannot look them up.
+func (v Validator) getExtensionService(namespace, name string) (extension *contourv1alpha1.ExtensionService, ok bool, err error) {
+	extensionStore, ok := v.Store.(ExtensionServiceStore)
+	if !ok {
+		return nil, false, nil
+	}
+
+	ctx, span := tracer().Start(v.context(), "Store.GetExtensionService")
+	if store, ok := v.store(ctx).(ExtensionServiceStore); ok {
+		extensionStore = store
+	}
+	extension, err = extensionStore.GetExtensionService(namespace, name)
+	span.SetAttributes(attribute.String("namespace", namespace), attribute.String("name", name))
+	endSpan(span, err)
+	return extension, true, err
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	fake "k8s.io/client-go/rest/fake"
+)
+
+// recordSpans installs a tracer provider and propagator for the duration of
+// the test and returns the recorder of the ended spans.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	defaultProvider := otel.GetTracerProvider()
+	defaultPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(defaultProvider)
+		otel.SetTextMapPropagator(defaultPropagator)
+	})
+
+	return recorder
+}
+
+func TestAdmissionMiddlewareTracing(t *testing.T) {
+	recorder := recordSpans(t)
+
+	existing := newTestProxy("team-a", "existing", "foo.bar.com")
+	handler := HTTPProxyAdmissionHandler{
+		Validator: Validator{
+			Store: &TestStore{
+				list: func() ([]contourv1.HTTPProxy, error) {
+					return []contourv1.HTTPProxy{existing}, nil
+				},
+			},
+			EnabledRules: []string{ruleFqdnConflict, ruleFqdnOwnership},
+		},
+	}
+
+	review := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "1234",
+			Kind:      httpProxyResource,
+			Name:      "new",
+			Namespace: "team-b",
+			Operation: admissionv1.Create,
+			Object: runtime.RawExtension{
+				Raw: []byte(`{"metadata": {"name": "new", "namespace": "team-b"}, "spec": {"virtualhost": {"fqdn": "foo.bar.com"}}}`),
+			},
+		},
+	}
+	review.SetGroupVersionKind(admissionv1.SchemeGroupVersion.WithKind("AdmissionReview"))
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	AdmissionMiddleware(handler.ValidateContext).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	names := map[string]string{}
+	parents := map[string][]string{}
+	for _, span := range spans {
+		if got := span.SpanContext().TraceID().String(); got != traceID {
+			t.Errorf("span %q is not part of the propagated trace: %s", span.Name(), got)
+		}
+		names[span.SpanContext().SpanID().String()] = span.Name()
+	}
+	for _, span := range spans {
+		parents[span.Name()] = append(parents[span.Name()], names[span.Parent().SpanID().String()])
+	}
+
+	expected := map[string][]string{
+		"AdmissionMiddleware":   {""},
+		"decode":                {"AdmissionMiddleware"},
+		"rule fqdn-conflict":    {"AdmissionMiddleware"},
+		"rule fqdn-ownership":   {"AdmissionMiddleware"},
+		"Store.ListHTTPProxies": {"rule fqdn-conflict"},
+		"encode":                {"AdmissionMiddleware"},
+	}
+	if diff := cmp.Diff(parents, expected); diff != "" {
+		t.Errorf("unexpected span parents (-got +want):\n%s", diff)
+	}
+
+	for _, span := range spans {
+		if span.Name() != "AdmissionMiddleware" {
+			continue
+		}
+		attributes := map[string]string{}
+		for _, kv := range span.Attributes() {
+			attributes[string(kv.Key)] = kv.Value.Emit()
+		}
+		for key, value := range map[string]string{
+			"admission.uid":     "1234",
+			"admission.allowed": "false",
+		} {
+			if attributes[key] != value {
+				t.Errorf("expected attribute %s=%s, got %q", key, value, attributes[key])
+			}
+		}
+	}
+}
+
+type contextKey struct{}
+
+func TestClusterStoreWithContext(t *testing.T) {
+	var got any
+	fakeClient := fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		got = req.Context().Value(contextKey{})
+		return nil, context.Canceled
+	})
+
+	c := discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{})
+	c.RESTClient().(*rest.RESTClient).Client = fakeClient
+
+	store := &ClusterStore{c.RESTClient()}
+	ctx := context.WithValue(context.Background(), contextKey{}, "request")
+	if _, err := store.WithContext(ctx).ListHTTPProxies(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if got != "request" {
+		t.Errorf("expected the request to use the bound context, got %v", got)
+	}
+}
+
+func TestTracingEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected bool
+	}{
+		{"unset", nil, false},
+		{"endpoint", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"}, true},
+		{"traces endpoint", map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://collector:4318/v1/traces"}, true},
+		{"otlp exporter", map[string]string{"OTEL_TRACES_EXPORTER": "otlp"}, true},
+		{"disabled", map[string]string{"OTEL_TRACES_EXPORTER": "none", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"} {
+				t.Setenv(key, tc.env[key])
+			}
+			if enabled := tracingEnabled(); enabled != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, enabled)
+			}
+		})
+	}
+}
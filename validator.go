@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"slices"
+	"strings"
 
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -12,23 +19,247 @@ const (
 	ingressClassAnnotation = "kubernetes.io/ingress.class"
 )
 
+const (
+	ruleFqdnConflict     = "fqdn-conflict"
+	ruleFqdnOwnership    = "fqdn-ownership"
+	ruleHostnameConflict = "hostname-conflict"
+)
+
+type rule struct {
+	name string
+	// field is the path reported for violations that do not set their own.
+	field string
+	check func(Validator, contourv1.HTTPProxy) (ValidationResponse, error)
+}
+
+// rules are evaluated in order for every proxy targetted by the validator.
+var rules = []rule{
+	{ruleFqdnConflict, "spec.virtualhost.fqdn", Validator.checkFqdnConflicts},
+	{ruleFqdnOwnership, "spec.virtualhost.fqdn", Validator.checkFqdnOwnership},
+	{ruleSharedFqdn, "metadata.annotations[" + sharedFqdnAnnotation + "]", Validator.checkSharedFqdn},
+	{ruleHostnameConflict, "spec.virtualhost.fqdn", Validator.checkHostnameConflicts},
+	{ruleClusterConflict, "spec.virtualhost.fqdn", Validator.checkClusterConflicts},
+	{ruleTimeoutPolicy, "spec.routes", Validator.checkTimeoutPolicies},
+	{ruleRetryPolicy, "spec.routes", Validator.checkRetryPolicies},
+	{ruleHeaderPolicy, "spec.routes", Validator.checkHeaderPolicies},
+	{ruleRateLimitPolicy, "spec.routes", Validator.checkRateLimitPolicies},
+	{ruleCORSPolicy, "spec.virtualhost.corsPolicy", Validator.checkCORSPolicy},
+	{ruleJWTPolicy, "spec.virtualhost.jwtProviders", Validator.checkJWTPolicies},
+	{ruleAuthorization, "spec.virtualhost.authorization", Validator.checkAuthorization},
+	{ruleTLSPolicy, "spec.virtualhost.tls", Validator.checkTLSPolicy},
+	{ruleMatchConditions, "spec.routes", Validator.checkMatchConditions},
+	{ruleLoadBalancerPolicy, "spec.routes", Validator.checkLoadBalancerPolicies},
+	{ruleHealthCheckPolicy, "spec.routes", Validator.checkHealthCheckPolicies},
+	{ruleServiceWeights, "spec.routes", Validator.checkServiceWeights},
+}
+
 type Validator struct {
 	Store                Store
 	TargetIngressClasses []string
+	// EnabledRules restricts validation to the named rules. All rules are
+	// evaluated when empty.
+	EnabledRules  []string
+	FqdnOwnership []FqdnOwnership
+	// CheckIngresses and CheckHTTPRoutes enable hostname conflict detection
+	// against Ingresses and HTTPRoutes when the Store supports listing them.
+	CheckIngresses  bool
+	CheckHTTPRoutes bool
+	// ProtectIncludedProxies denies deleting proxies that are still included
+	// by another proxy.
+	ProtectIncludedProxies bool
+	// SharedFqdns lists per remote cluster the fqdns that may be served by
+	// both that cluster and the local cluster.
+	SharedFqdns map[string][]string
+	// Limits caps the values proxies may configure.
+	Limits Limits
+	// HeaderPolicy restricts the headers proxies may set or remove.
+	HeaderPolicy HeaderPolicy
+	// RateLimits bounds the rate limit policies of proxies.
+	RateLimits RateLimits
+	// CORS restricts the CORS policies of virtual hosts.
+	CORS CORSRestrictions
+	// Authorization restricts the external authorization of proxies.
+	Authorization AuthorizationRestrictions
+	// TLS sets the TLS baseline of root proxies.
+	TLS TLSRestrictions
+	// OldProxy is the proxy an UPDATE replaces, nil when validating any other
+	// operation or outside of admission.
+	OldProxy *contourv1.HTTPProxy
+	// Logger records the outcome of each rule. The admission handler sets a
+	// logger per request, nothing is logged when nil.
+	Logger *slog.Logger
+	// Context is the context of the admission request, used to trace rules
+	// and Store calls as part of the request.
+	Context context.Context
 }
 
 type ValidationResponse struct {
 	Valid  bool
 	Reason string
+	// Violations lists each problem found by the rules, the Reason summarizes
+	// them.
+	Violations []Violation
+	// Conflicts are the existing proxies claiming the same fqdn.
+	Conflicts []contourv1.HTTPProxy
+}
+
+// Violation is a single rule failure shared by the admission response and
+// the lint reports.
+type Violation struct {
+	Rule string `json:"rule"`
+	// Field is the path of the offending field, e.g. spec.virtualhost.fqdn
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
 }
 
 func (v Validator) IsValidProxy(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	var fqdn string
+	if proxy.Spec.VirtualHost != nil {
+		fqdn = proxy.Spec.VirtualHost.Fqdn
+	}
+	logger := v.logger().With("fqdn", fqdn, "ingressClass", proxyIngressClass(proxy))
+
 	if !v.proxyMatchesTargetIngressClasses(proxy) {
+		logger.Info("Proxy validated", "ingressClassMatch", false, "valid", true)
+		return ValidationResponse{
+			Valid: true,
+		}, nil
+	}
+
+	var reasons []string
+	var violations []Violation
+	var conflicts []contourv1.HTTPProxy
+	var outcomes []any
+	for _, r := range rules {
+		if !v.ruleEnabled(r.name) {
+			outcomes = append(outcomes, slog.String(r.name, "disabled"))
+			continue
+		}
+
+		ctx, span := tracer().Start(v.context(), "rule "+r.name, trace.WithAttributes(attribute.String("rule", r.name)))
+		ruleValidator := v
+		ruleValidator.Context = ctx
+		resp, err := r.check(ruleValidator, proxy)
+		span.SetAttributes(attribute.Bool("valid", resp.Valid))
+		endSpan(span, err)
+		if err != nil {
+			logger.Error("Rule failed", "rule", r.name, "error", err.Error())
+			return resp, err
+		}
+		if resp.Valid {
+			outcomes = append(outcomes, slog.String(r.name, "pass"))
+			continue
+		}
+		outcomes = append(outcomes, slog.String(r.name, "fail"))
+
+		reasons = append(reasons, resp.Reason)
+		conflicts = append(conflicts, resp.Conflicts...)
+		if len(resp.Violations) == 0 {
+			resp.Violations = []Violation{{Field: r.field, Message: resp.Reason}}
+		}
+		for _, violation := range resp.Violations {
+			violation.Rule = r.name
+			violations = append(violations, violation)
+		}
+	}
+
+	logger.Info("Proxy validated", "ingressClassMatch", true, slog.Group("rules", outcomes...), "valid", len(reasons) == 0)
+
+	if len(reasons) > 0 {
+		return ValidationResponse{
+			Valid:      false,
+			Reason:     strings.Join(reasons, "; "),
+			Violations: violations,
+			Conflicts:  conflicts,
+		}, nil
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}, nil
+}
+
+// violationCollector collects the violations a rule finds in the spec of a
+// resource. The rule is left empty for violations of proxy rules, which
+// IsValidProxy fills in.
+type violationCollector struct {
+	rule       string
+	violations []Violation
+}
+
+// invalid records a violation of a field of the spec.
+func (c *violationCollector) invalid(field, format string, args ...any) {
+	c.violations = append(c.violations, Violation{
+		Rule:    c.rule,
+		Field:   "spec." + field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// violationsResponse returns the response for the named resource, invalid
+// when there are violations.
+func violationsResponse(name string, violations []Violation) ValidationResponse {
+	if len(violations) > 0 {
+		return ValidationResponse{
+			Valid:      false,
+			Violations: violations,
+			Reason:     fmt.Sprintf("%s is invalid: %s", name, strings.Join(violationMessages(violations), "; ")),
+		}
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}
+}
+
+func violationMessages(violations []Violation) []string {
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.Message)
+	}
+	return messages
+}
+
+func (v Validator) logger() *slog.Logger {
+	if v.Logger == nil {
+		return discardLogger
+	}
+	return v.Logger
+}
+
+func (v Validator) ruleEnabled(name string) bool {
+	return len(v.EnabledRules) == 0 || slices.Contains(v.EnabledRules, name)
+}
+
+// namespaceSelected reports whether the namespace matches the selector. No
+// namespace is selected by a nil selector, nor are namespaces that cannot be
+// found.
+func (v Validator) namespaceSelected(selector *metav1.LabelSelector, name string) (bool, error) {
+	if selector == nil {
+		return false, nil
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+
+	namespace, err := v.getNamespace(name)
+	if err != nil || namespace == nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(namespace.Labels)), nil
+}
+
+func (v Validator) checkFqdnConflicts(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	// Only root proxies claim an fqdn
+	if proxy.Spec.VirtualHost == nil {
 		return ValidationResponse{
 			Valid: true,
 		}, nil
 	}
-	proxies, err := v.Store.ListHTTPProxies()
+
+	proxies, err := v.listHTTPProxies()
 	if err != nil {
 		return ValidationResponse{
 			Valid:  false,
@@ -37,38 +268,99 @@ func (v Validator) IsValidProxy(proxy contourv1.HTTPProxy) (ValidationResponse,
 	}
 
 	var conflictingProxies []string
+	var conflicts []contourv1.HTTPProxy
 
 	for _, p := range proxies {
-		// TODO: Handle non-root httpproxy ?
-		//
+		// The proxy does not conflict with a stored version of itself
+		if p.Namespace == proxy.Namespace && p.Name == proxy.Name {
+			continue
+		}
+
 		// HTTPProxy must be targetted by contour to
 		// be in conflict
-		if v.proxyMatchesTargetIngressClasses(p) &&
-			p.Spec.VirtualHost.Fqdn == proxy.Spec.VirtualHost.Fqdn {
+		if p.Spec.VirtualHost != nil &&
+			v.proxyMatchesTargetIngressClasses(p) &&
+			normalizeFqdn(p.Spec.VirtualHost.Fqdn) == normalizeFqdn(proxy.Spec.VirtualHost.Fqdn) &&
+			!v.sharesFqdn(proxy, p) {
 			conflictingProxies = append(conflictingProxies, p.Name)
+			conflicts = append(conflicts, p)
 		}
 	}
 
 	if len(conflictingProxies) > 0 {
 		return ValidationResponse{
-			Valid:  false,
-			Reason: fmt.Sprintf("%s is in conflict with %v", proxy.Name, conflictingProxies),
+			Valid:     false,
+			Reason:    fmt.Sprintf("%s is in conflict with %v", proxy.Name, conflictingProxies),
+			Conflicts: conflicts,
+		}, nil
+	}
+
+	return ValidationResponse{
+		Valid: true,
+	}, nil
+}
+
+func (v Validator) checkFqdnOwnership(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	if proxy.Spec.VirtualHost == nil {
+		return ValidationResponse{
+			Valid: true,
 		}, nil
 	}
 
+	fqdn := proxy.Spec.VirtualHost.Fqdn
+	for _, ownership := range v.FqdnOwnership {
+		if !matchFqdn(ownership.Fqdn, fqdn) {
+			continue
+		}
+		if !slices.Contains(ownership.Namespaces, proxy.Namespace) {
+			return ValidationResponse{
+				Valid:  false,
+				Reason: fmt.Sprintf("namespace %q is not allowed to claim %s", proxy.Namespace, fqdn),
+			}, nil
+		}
+	}
+
 	return ValidationResponse{
 		Valid: true,
 	}, nil
 }
 
+// matchFqdn reports whether the fqdn matches the pattern, which is either an
+// exact fqdn or a wildcard matching a single leading label, e.g. *.example.com
+func matchFqdn(pattern, fqdn string) bool {
+	pattern = strings.ToLower(pattern)
+	fqdn = strings.ToLower(fqdn)
+
+	suffix, isWildcard := strings.CutPrefix(pattern, "*")
+	if !isWildcard {
+		return pattern == fqdn
+	}
+
+	label, found := strings.CutSuffix(fqdn, suffix)
+	return found && label != "" && !strings.Contains(label, ".")
+}
+
 func (v Validator) proxyMatchesTargetIngressClasses(proxy contourv1.HTTPProxy) bool {
+	return v.matchesTargetIngressClasses(proxy.GetAnnotations(), proxy.Spec.IngressClassName)
+}
+
+// proxyIngressClass returns the ingress class of the proxy, preferring the
+// annotation over the spec like contour does.
+func proxyIngressClass(p contourv1.HTTPProxy) string {
+	if class := p.GetAnnotations()[ingressClassAnnotation]; class != "" {
+		return class
+	}
+	return p.Spec.IngressClassName
+}
+
+func (v Validator) matchesTargetIngressClasses(annotations map[string]string, className string) bool {
 	// First check if the ingress class annotation is set to a non-zero value since
 	// it takes precendence over the spec ingress class name
-	if annotationClassValue := proxy.GetAnnotations()[ingressClassAnnotation]; annotationClassValue != "" {
+	if annotationClassValue := annotations[ingressClassAnnotation]; annotationClassValue != "" {
 		return v.matchIngressClass(annotationClassValue)
 	}
 
-	return v.matchIngressClass(proxy.Spec.IngressClassName)
+	return v.matchIngressClass(className)
 }
 
 func (v Validator) matchIngressClass(className string) bool {
@@ -6,16 +6,28 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 type TestStore struct {
-	list func() ([]contourv1.HTTPProxy, error)
+	list      func() ([]contourv1.HTTPProxy, error)
+	ingresses func() ([]networkingv1.Ingress, error)
+	routes    func() ([]gatewayv1beta1.HTTPRoute, error)
 }
 
 func (ts *TestStore) ListHTTPProxies() ([]contourv1.HTTPProxy, error) {
 	return ts.list()
 }
 
+func (ts *TestStore) ListIngresses() ([]networkingv1.Ingress, error) {
+	return ts.ingresses()
+}
+
+func (ts *TestStore) ListHTTPRoutes() ([]gatewayv1beta1.HTTPRoute, error) {
+	return ts.routes()
+}
+
 func TestIsValidProxy(t *testing.T) {
 	p1 := contourv1.HTTPProxy{
 		Spec: contourv1.HTTPProxySpec{
@@ -122,6 +134,29 @@ func TestIsValidProxy(t *testing.T) {
 			ValidationResponse{
 				Valid:  false,
 				Reason: "proxy-under-test is in conflict with [proxy2]",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [proxy2]"},
+				},
+				Conflicts: []contourv1.HTTPProxy{p2},
+			},
+		},
+		{
+			"proxy ingress classes targetted by validator, conflicting fqdn differs in case and trailing dot",
+			contourv1.HTTPProxy{
+				Spec: contourv1.HTTPProxySpec{
+					IngressClassName: "targetted",
+					VirtualHost: &contourv1.VirtualHost{
+						Fqdn: "Foo.Baz.com.",
+					},
+				},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is in conflict with [proxy2]",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [proxy2]"},
+				},
+				Conflicts: []contourv1.HTTPProxy{p2},
 			},
 		},
 		{
@@ -137,6 +172,10 @@ func TestIsValidProxy(t *testing.T) {
 			ValidationResponse{
 				Valid:  false,
 				Reason: "proxy-under-test is in conflict with [proxy4]",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [proxy4]"},
+				},
+				Conflicts: []contourv1.HTTPProxy{p4},
 			},
 		},
 	}
@@ -222,6 +261,10 @@ func TestIsValidProxyDefaultIngressClasses(t *testing.T) {
 			ValidationResponse{
 				Valid:  false,
 				Reason: "proxy-under-test is in conflict with [proxy1]",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [proxy1]"},
+				},
+				Conflicts: []contourv1.HTTPProxy{p1},
 			},
 		},
 		{
@@ -237,6 +280,10 @@ func TestIsValidProxyDefaultIngressClasses(t *testing.T) {
 			ValidationResponse{
 				Valid:  false,
 				Reason: "proxy-under-test is in conflict with [proxy1]",
+				Violations: []Violation{
+					{Rule: ruleFqdnConflict, Field: "spec.virtualhost.fqdn", Message: "proxy-under-test is in conflict with [proxy1]"},
+				},
+				Conflicts: []contourv1.HTTPProxy{p1},
 			},
 		},
 	}
@@ -298,3 +345,213 @@ func TestIsValidProxyStoreError(t *testing.T) {
 		t.Errorf("ValidationResponse: (-got +want)\n%s", diff)
 	}
 }
+
+func TestIsValidProxyFqdnOwnership(t *testing.T) {
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, nil
+		},
+	}
+
+	validator := Validator{
+		Store: store,
+		FqdnOwnership: []FqdnOwnership{
+			{Fqdn: "*.team-a.com", Namespaces: []string{"team-a"}},
+			{Fqdn: "shared.com", Namespaces: []string{"team-a", "team-b"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		fqdn      string
+		expected  ValidationResponse
+	}{
+		{
+			"wildcard owner",
+			"team-a",
+			"app.team-a.com",
+			ValidationResponse{Valid: true},
+		},
+		{
+			"wildcard not owner",
+			"team-b",
+			"app.TEAM-A.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: `namespace "team-b" is not allowed to claim app.TEAM-A.com`,
+				Violations: []Violation{
+					{Rule: ruleFqdnOwnership, Field: "spec.virtualhost.fqdn", Message: `namespace "team-b" is not allowed to claim app.TEAM-A.com`},
+				},
+			},
+		},
+		{
+			"wildcard matches single label only",
+			"team-b",
+			"a.b.team-a.com",
+			ValidationResponse{Valid: true},
+		},
+		{
+			"exact owner",
+			"team-b",
+			"shared.com",
+			ValidationResponse{Valid: true},
+		},
+		{
+			"exact not owner",
+			"team-c",
+			"shared.com",
+			ValidationResponse{
+				Valid:  false,
+				Reason: `namespace "team-c" is not allowed to claim shared.com`,
+				Violations: []Violation{
+					{Rule: ruleFqdnOwnership, Field: "spec.virtualhost.fqdn", Message: `namespace "team-c" is not allowed to claim shared.com`},
+				},
+			},
+		},
+		{
+			"no ownership configured",
+			"team-c",
+			"foo.bar.com",
+			ValidationResponse{Valid: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := contourv1.HTTPProxy{
+				Spec: contourv1.HTTPProxySpec{
+					VirtualHost: &contourv1.VirtualHost{
+						Fqdn: tt.fqdn,
+					},
+				},
+			}
+			proxy.SetName("proxy-under-test")
+			proxy.SetNamespace(tt.namespace)
+
+			resp, err := validator.IsValidProxy(proxy)
+			if err != nil {
+				t.Fatalf("unexpected error validating proxy: %s", err.Error())
+			}
+
+			if diff := cmp.Diff(resp, tt.expected); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyEnabledRules(t *testing.T) {
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return nil, errors.New("store should not be queried")
+		},
+	}
+
+	validator := Validator{
+		Store:        store,
+		EnabledRules: []string{ruleFqdnOwnership},
+	}
+
+	proxy := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "foo.bar.com",
+			},
+		},
+	}
+
+	resp, err := validator.IsValidProxy(proxy)
+	if err != nil {
+		t.Fatalf("unexpected error validating proxy: %s", err.Error())
+	}
+
+	if diff := cmp.Diff(resp, ValidationResponse{Valid: true}); diff != "" {
+		t.Errorf("ValidationResponse: (-got +want)\n%s", diff)
+	}
+}
+
+func TestIsValidProxyStoredVersion(t *testing.T) {
+	stored := contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			VirtualHost: &contourv1.VirtualHost{
+				Fqdn: "foo.bar.com",
+			},
+		},
+	}
+	stored.SetName("proxy-under-test")
+	stored.SetNamespace("default")
+
+	child := contourv1.HTTPProxy{}
+	child.SetName("child")
+	child.SetNamespace("default")
+
+	store := &TestStore{
+		list: func() ([]contourv1.HTTPProxy, error) {
+			return []contourv1.HTTPProxy{stored, child}, nil
+		},
+	}
+
+	validator := Validator{
+		Store: store,
+	}
+
+	tests := []struct {
+		name  string
+		proxy contourv1.HTTPProxy
+	}{
+		{"update of stored proxy", *stored.DeepCopy()},
+		{"non-root proxy", *child.DeepCopy()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := validator.IsValidProxy(tt.proxy)
+			if err != nil {
+				t.Fatalf("unexpected error validating proxy: %s", err.Error())
+			}
+
+			if diff := cmp.Diff(resp, ValidationResponse{Valid: true}); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestViolationsResponse(t *testing.T) {
+	collector := violationCollector{rule: ruleTLSCertificateDelegation}
+	collector.invalid("delegations[0].secretName", "%s is required", "secretName")
+	collector.invalid("delegations[1].targetNamespaces", "no target namespaces")
+
+	tests := []struct {
+		name       string
+		violations []Violation
+		expected   ValidationResponse
+	}{
+		{
+			"no violations",
+			nil,
+			ValidationResponse{Valid: true},
+		},
+		{
+			"violations",
+			collector.violations,
+			ValidationResponse{
+				Valid:  false,
+				Reason: "resource is invalid: secretName is required; no target namespaces",
+				Violations: []Violation{
+					{Rule: ruleTLSCertificateDelegation, Field: "spec.delegations[0].secretName", Message: "secretName is required"},
+					{Rule: ruleTLSCertificateDelegation, Field: "spec.delegations[1].targetNamespaces", Message: "no target namespaces"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(violationsResponse("resource", tt.violations), tt.expected); diff != "" {
+				t.Errorf("ValidationResponse %s: (-got +want)\n%s", tt.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const ruleServiceWeights = "service-weights"
+
+// trafficShares returns the share of the traffic each service receives, keyed
+// by name and port. Contour splits the traffic evenly when no service sets a
+// weight, mirrors receive a copy of the traffic and are left out.
+func trafficShares(services []contourv1.Service) map[string]float64 {
+	var total int64
+	count := 0
+	for _, service := range services {
+		if !service.Mirror {
+			total += service.Weight
+			count++
+		}
+	}
+
+	shares := map[string]float64{}
+	for _, service := range services {
+		if service.Mirror {
+			continue
+		}
+		share := 1 / float64(count)
+		if total > 0 {
+			share = float64(service.Weight) / float64(total)
+		}
+		shares[fmt.Sprintf("%s:%d", service.Name, service.Port)] += share
+	}
+	return shares
+}
+
+// trafficShift returns the percentage of the traffic moving to other
+// services when the shares change.
+func trafficShift(previous, current map[string]float64) float64 {
+	var moved float64
+	for key, share := range current {
+		moved += math.Abs(share - previous[key])
+	}
+	for key, share := range previous {
+		if _, found := current[key]; !found {
+			moved += share
+		}
+	}
+	return moved / 2 * 100
+}
+
+func (v Validator) checkServiceWeights(proxy contourv1.HTTPProxy) (ValidationResponse, error) {
+	collector := violationCollector{}
+	invalid := collector.invalid
+
+	// checkWeights requires weights on all services receiving traffic or on
+	// none of them, as contour sends no traffic to a service without a
+	// weight once another service sets one.
+	checkWeights := func(path string, services []contourv1.Service) {
+		weighted := false
+		for _, service := range services {
+			weighted = weighted || (!service.Mirror && service.Weight > 0)
+		}
+		for j, service := range services {
+			field := fmt.Sprintf("%s.services[%d].weight", path, j)
+			switch {
+			case service.Weight < 0:
+				invalid(field, "%s %d must not be negative", field, service.Weight)
+			case service.Mirror:
+				if service.Weight > 100 {
+					invalid(field, "%s %d: the weight of a mirror is a percentage between 1 and 100", field, service.Weight)
+				}
+			case weighted && service.Weight == 0:
+				invalid(field, "%s is required as other services set a weight, %s receives no traffic without one", field, service.Name)
+			}
+		}
+	}
+
+	for i, route := range proxy.Spec.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+		checkWeights(path, route.Services)
+
+		mirrors := 0
+		for j, service := range route.Services {
+			if !service.Mirror {
+				continue
+			}
+			mirrors++
+			if mirrors > 1 {
+				field := fmt.Sprintf("%s.services[%d].mirror", path, j)
+				invalid(field, "%s: only one service per route can be a mirror", field)
+			}
+		}
+		if mirrors > 0 && mirrors == len(route.Services) {
+			invalid(path+".services", "%s.services: a route needs a service that is not a mirror", path)
+		}
+	}
+	if tcp := proxy.Spec.TCPProxy; tcp != nil {
+		checkWeights("tcpproxy", tcp.Services)
+	}
+
+	// An update may only move part of the traffic of a route between its
+	// services at once.
+	if old, maximum := v.OldProxy, v.Limits.MaxTrafficShiftPercent; old != nil && maximum > 0 {
+		checkShift := func(path string, oldServices, services []contourv1.Service) {
+			oldShares, shares := trafficShares(oldServices), trafficShares(services)
+			if len(oldShares) == 0 || len(shares) == 0 {
+				return
+			}
+			if shift := trafficShift(oldShares, shares); shift > float64(maximum) {
+				invalid(path+".services", "%s.services: the update moves %.0f%% of the traffic, more than the maximum of %d%%", path, shift, maximum)
+			}
+		}
+
+		// Routes are matched by their conditions, as they may be reordered.
+		for i, route := range proxy.Spec.Routes {
+			for _, oldRoute := range old.Spec.Routes {
+				if equality.Semantic.DeepEqual(route.Conditions, oldRoute.Conditions) {
+					checkShift(fmt.Sprintf("routes[%d]", i), oldRoute.Services, route.Services)
+					break
+				}
+			}
+		}
+		if proxy.Spec.TCPProxy != nil && old.Spec.TCPProxy != nil {
+			checkShift("tcpproxy", old.Spec.TCPProxy.Services, proxy.Spec.TCPProxy.Services)
+		}
+	}
+
+	return violationsResponse(proxy.Name, collector.violations), nil
+}
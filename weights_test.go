@@ -0,0 +1,150 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestIsValidProxyServiceWeights(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []contourv1.Service
+		expected ValidationResponse
+	}{
+		{
+			"even split",
+			[]contourv1.Service{{Name: "blue", Port: 80}, {Name: "green", Port: 80}},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"explicit weights and a mirror",
+			[]contourv1.Service{{Name: "blue", Port: 80, Weight: 90}, {Name: "green", Port: 80, Weight: 10}, {Name: "shadow", Port: 80, Mirror: true}},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"missing weight",
+			[]contourv1.Service{{Name: "blue", Port: 80, Weight: 100}, {Name: "green", Port: 80}, {Name: "canary", Port: 80, Weight: -5}},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].services[1].weight is required as other services set a weight, green receives no traffic without one; routes[0].services[2].weight -5 must not be negative",
+				Violations: []Violation{
+					{Rule: ruleServiceWeights, Field: "spec.routes[0].services[1].weight", Message: "routes[0].services[1].weight is required as other services set a weight, green receives no traffic without one"},
+					{Rule: ruleServiceWeights, Field: "spec.routes[0].services[2].weight", Message: "routes[0].services[2].weight -5 must not be negative"},
+				},
+			},
+		},
+		{
+			"mirrors only",
+			[]contourv1.Service{{Name: "shadow", Port: 80, Mirror: true, Weight: 150}, {Name: "audit", Port: 80, Mirror: true}},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].services[0].weight 150: the weight of a mirror is a percentage between 1 and 100; routes[0].services[1].mirror: only one service per route can be a mirror; routes[0].services: a route needs a service that is not a mirror",
+				Violations: []Violation{
+					{Rule: ruleServiceWeights, Field: "spec.routes[0].services[0].weight", Message: "routes[0].services[0].weight 150: the weight of a mirror is a percentage between 1 and 100"},
+					{Rule: ruleServiceWeights, Field: "spec.routes[0].services[1].mirror", Message: "routes[0].services[1].mirror: only one service per route can be a mirror"},
+					{Rule: ruleServiceWeights, Field: "spec.routes[0].services", Message: "routes[0].services: a route needs a service that is not a mirror"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleServiceWeights},
+			}
+
+			resp, err := validator.IsValidProxy(newRouteProxy(contourv1.Route{Services: tc.services}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyServiceWeightsTrafficShift(t *testing.T) {
+	api := []contourv1.MatchCondition{{Prefix: "/api"}}
+	old := newRouteProxy(
+		contourv1.Route{Services: []contourv1.Service{{Name: "web", Port: 80}}},
+		contourv1.Route{Conditions: api, Services: []contourv1.Service{{Name: "blue", Port: 80, Weight: 90}, {Name: "green", Port: 80, Weight: 10}}},
+	)
+
+	tests := []struct {
+		name     string
+		routes   []contourv1.Route
+		expected ValidationResponse
+	}{
+		{
+			"small shift",
+			[]contourv1.Route{
+				{Conditions: api, Services: []contourv1.Service{{Name: "blue", Port: 80, Weight: 70}, {Name: "green", Port: 80, Weight: 30}}},
+				{Services: []contourv1.Service{{Name: "web", Port: 80}}},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"new route",
+			[]contourv1.Route{
+				{Conditions: []contourv1.MatchCondition{{Prefix: "/v2"}}, Services: []contourv1.Service{{Name: "green", Port: 80}}},
+			},
+			ValidationResponse{Valid: true},
+		},
+		{
+			"large shift",
+			[]contourv1.Route{
+				{Services: []contourv1.Service{{Name: "web", Port: 80}, {Name: "web-v2", Port: 80}}},
+				{Conditions: api, Services: []contourv1.Service{{Name: "blue", Port: 80, Weight: 50}, {Name: "green", Port: 80, Weight: 50}}},
+			},
+			ValidationResponse{
+				Valid:  false,
+				Reason: "proxy-under-test is invalid: routes[0].services: the update moves 50% of the traffic, more than the maximum of 25%; routes[1].services: the update moves 40% of the traffic, more than the maximum of 25%",
+				Violations: []Violation{
+					{Rule: ruleServiceWeights, Field: "spec.routes[0].services", Message: "routes[0].services: the update moves 50% of the traffic, more than the maximum of 25%"},
+					{Rule: ruleServiceWeights, Field: "spec.routes[1].services", Message: "routes[1].services: the update moves 40% of the traffic, more than the maximum of 25%"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := Validator{
+				Store:        &MemoryStore{},
+				EnabledRules: []string{ruleServiceWeights},
+				Limits:       Limits{MaxTrafficShiftPercent: 25},
+				OldProxy:     &old,
+			}
+
+			resp, err := validator.IsValidProxy(newRouteProxy(tc.routes...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(resp, tc.expected); diff != "" {
+				t.Errorf("IsValidProxy: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValidProxyServiceWeightsTrafficShiftCreate(t *testing.T) {
+	stored := newRouteProxy(contourv1.Route{Services: []contourv1.Service{{Name: "blue", Port: 80, Weight: 100}}})
+	validator := Validator{
+		Store:        &MemoryStore{Proxies: []contourv1.HTTPProxy{stored}},
+		EnabledRules: []string{ruleServiceWeights},
+		Limits:       Limits{MaxTrafficShiftPercent: 25},
+	}
+
+	resp, err := validator.IsValidProxy(newRouteProxy(contourv1.Route{Services: []contourv1.Service{{Name: "green", Port: 80, Weight: 100}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Valid {
+		t.Errorf("expected shifts to only be checked on updates, got %q", resp.Reason)
+	}
+}